@@ -0,0 +1,108 @@
+package spaserve
+
+import (
+	"path"
+	"regexp"
+)
+
+// CacheControlPolicy decides the Cache-Control header for every file served by the StaticFilesHandler.
+// Overrides are checked first, then the entry document, then fingerprinted files and finally the default.
+type CacheControlPolicy struct {
+	// Immutable is the value for fingerprinted files
+	Immutable string
+	// Document is the value for index.html and SPA fallback responses
+	Document string
+	// Default is the value for all other files, an empty value does not set the header
+	Default string
+	// HashPattern detects fingerprinted files by their base name (e.g. index-BxY3k9aQ.js). If a submatch named "hash"
+	// participates in the match, it must also contain a digit or both upper and lower case letters to tell hashes
+	// from words.
+	HashPattern *regexp.Regexp
+	// ImmutableGlobs marks files matching any of the globs as fingerprinted (e.g. assets/**)
+	ImmutableGlobs []string
+	// Overrides sets the value for files matching a glob, the first matching rule wins
+	Overrides []CacheControlRule
+}
+
+// CacheControlRule sets the Cache-Control value for files matching the glob.
+type CacheControlRule struct {
+	Glob  string
+	Value string
+}
+
+// defaultHashPattern matches file names with a hash directly before the extension as emitted by Vite, which appends
+// 8 base64url characters (index-BxY3k9aQ.js, index-B-xY3k9a.js), or webpack, which appends at least 8 hex digits
+// (main.3f2a1b4c.js)
+var defaultHashPattern = regexp.MustCompile(`(?:-(?P<hash>[A-Za-z0-9_-]{8})|\.[0-9a-f]{8,})\.[A-Za-z0-9]+$`)
+
+// DefaultCacheControlPolicy returns a policy which caches fingerprinted files forever and revalidates the entry document.
+func DefaultCacheControlPolicy() CacheControlPolicy {
+	return CacheControlPolicy{
+		Immutable:   "public, max-age=31536000, immutable",
+		Document:    "no-cache",
+		Default:     "",
+		HashPattern: defaultHashPattern,
+	}
+}
+
+// value returns the Cache-Control value for the given file name
+//   - name: the file name relative to the root of the file system
+//   - isDocument: true if the entry document is served, either directly or as SPA fallback
+func (p CacheControlPolicy) value(name string, isDocument bool) string {
+	for _, rule := range p.Overrides {
+		if matchGlob(rule.Glob, name) {
+			return rule.Value
+		}
+	}
+
+	if isDocument {
+		return p.Document
+	}
+
+	if p.isFingerprinted(name) {
+		return p.Immutable
+	}
+
+	return p.Default
+}
+
+// isFingerprinted returns true if the file name matches the immutable globs or the hash pattern
+func (p CacheControlPolicy) isFingerprinted(name string) bool {
+	for _, glob := range p.ImmutableGlobs {
+		if matchGlob(glob, name) {
+			return true
+		}
+	}
+
+	if p.HashPattern == nil {
+		return false
+	}
+
+	base := path.Base(name)
+	match := p.HashPattern.FindStringSubmatchIndex(base)
+	if match == nil {
+		return false
+	}
+
+	// words like app-manifest.json or my-component.js are not hashes
+	if i := p.HashPattern.SubexpIndex("hash"); i >= 0 && match[2*i] >= 0 {
+		return looksLikeHash(base[match[2*i]:match[2*i+1]])
+	}
+	return true
+}
+
+// looksLikeHash returns true if s contains a digit or both upper and lower case letters
+func looksLikeHash(s string) bool {
+	var upper, lower bool
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			return true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		}
+	}
+	return upper && lower
+}
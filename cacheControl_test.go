@@ -0,0 +1,42 @@
+package spaserve
+
+import "testing"
+
+func TestCacheControlPolicy(t *testing.T) {
+	policy := DefaultCacheControlPolicy()
+	policy.Default = "public, max-age=3600"
+	policy.ImmutableGlobs = []string{"fonts/**"}
+	policy.Overrides = []CacheControlRule{
+		{Glob: "sw.js", Value: "no-store"},
+	}
+
+	tt := []struct {
+		name       string
+		file       string
+		isDocument bool
+		want       string
+	}{
+		{name: "entry document", file: "index.html", isDocument: true, want: "no-cache"},
+		{name: "vite hashed asset", file: "assets/index-BxY3k9aQ.js", want: policy.Immutable},
+		{name: "vite hashed asset with dash", file: "assets/index-B-xY3k9a.js", want: policy.Immutable},
+		{name: "webpack hashed asset", file: "static/js/main.3f2a1b4c.js", want: policy.Immutable},
+		{name: "immutable glob", file: "fonts/inter.woff2", want: policy.Immutable},
+		{name: "unhashed asset", file: "favicon.ico", want: policy.Default},
+		{name: "unhashed asset with dash", file: "apple-touch-icon.png", want: policy.Default},
+		{name: "unhashed asset with long words", file: "apple-touch-icon-precomposed.png", want: policy.Default},
+		{name: "unhashed manifest", file: "app-manifest.json", want: policy.Default},
+		{name: "unhashed component", file: "assets/my-component.js", want: policy.Default},
+		{name: "unhashed apple touch icon", file: "apple-touch-icon-180x180.png", want: policy.Default},
+		{name: "unhashed android icon", file: "android-chrome-192x192.png", want: policy.Default},
+		{name: "unhashed favicon", file: "favicon-32x32.png", want: policy.Default},
+		{name: "override", file: "sw.js", want: "no-store"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := policy.value(tc.file, tc.isDocument); got != tc.want {
+				t.Errorf("value(%q, %v) = %q, want %q", tc.file, tc.isDocument, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Command spaserve-dts generates a TypeScript declaration file (.d.ts) for the web environment struct passed to
+// spaserve.WithInjectWebEnv, so the frontend types can not drift from the Go struct.
+//
+// It has to run inside the module of the struct, e.g. with go generate:
+//
+//	//go:generate go run github.com/jrschumacher/go-spaserve/cmd/spaserve-dts -pkg github.com/acme/app/config -type Env -ns APP_ENV -o ui/src/env.d.ts
+//
+// The struct is reflected by a temporary program calling spaserve.GenerateTypeScript, which is built with go run.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+var identRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var programTemplate = template.Must(template.New("main").Parse(`// Code generated by spaserve-dts. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	spaserve "github.com/jrschumacher/go-spaserve"
+	target {{ printf "%q" .Pkg }}
+)
+
+func main() {
+	b, err := spaserve.GenerateTypeScript(*new(target.{{ .Type }}), {{ printf "%q" .Namespace }})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Stdout.Write(b)
+}
+`))
+
+type config struct {
+	Pkg       string
+	Type      string
+	Namespace string
+	Output    string
+}
+
+func main() {
+	var c config
+	flag.StringVar(&c.Pkg, "pkg", "", "import path of the package declaring the web environment type (required)")
+	flag.StringVar(&c.Type, "type", "", "name of the web environment type (required)")
+	flag.StringVar(&c.Namespace, "ns", "APP_ENV", "namespace of the web environment on window")
+	flag.StringVar(&c.Output, "o", "", "output file, defaults to stdout")
+	flag.Parse()
+
+	if err := run(c); err != nil {
+		fmt.Fprintln(os.Stderr, "spaserve-dts:", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the declaration and writes it to the output
+func run(c config) error {
+	program, err := renderProgram(c)
+	if err != nil {
+		return err
+	}
+
+	// the program has to be inside the current module to resolve the package
+	dir, err := os.MkdirTemp(".", ".spaserve-dts-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), program, 0o644); err != nil {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", "run", "./"+filepath.ToSlash(dir))
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not reflect %s.%s: %w", c.Pkg, c.Type, err)
+	}
+
+	if c.Output == "" {
+		_, err := os.Stdout.Write(stdout.Bytes())
+		return err
+	}
+	return os.WriteFile(c.Output, stdout.Bytes(), 0o644)
+}
+
+// renderProgram validates the config and renders the program reflecting the type
+func renderProgram(c config) ([]byte, error) {
+	if c.Pkg == "" || c.Type == "" {
+		return nil, errors.New("-pkg and -type are required")
+	}
+	if !identRegex.MatchString(c.Type) {
+		return nil, fmt.Errorf("-type must be an identifier: %q", c.Type)
+	}
+
+	var b bytes.Buffer
+	if err := programTemplate.Execute(&b, c); err != nil {
+		return nil, err
+	}
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderProgram(t *testing.T) {
+	tt := []struct {
+		name    string
+		config  config
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "renders the program",
+			config: config{Pkg: "github.com/acme/app/config", Type: "Env", Namespace: "APP_ENV"},
+			want:   []string{`target "github.com/acme/app/config"`, `spaserve.GenerateTypeScript(*new(target.Env), "APP_ENV")`},
+		},
+		{
+			name:    "requires a package",
+			config:  config{Type: "Env"},
+			wantErr: true,
+		},
+		{
+			name:    "requires an identifier as type",
+			config:  config{Pkg: "github.com/acme/app/config", Type: "Env{}); os.Exit(0); _ = (x"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := renderProgram(tc.config)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("renderProgram() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("renderProgram() = %s, want it to contain %s", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package spaserve
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/psanford/memfs"
+)
+
+// compressibleExts are the file extensions which are gzip compressed by CompressFileSys
+var compressibleExts = map[string]bool{
+	".js":   true,
+	".mjs":  true,
+	".css":  true,
+	".html": true,
+	".svg":  true,
+	".json": true,
+	".wasm": true,
+}
+
+// CompressFileSys writes a gzip sidecar (e.g. app.js.gz) next to every compressible file of the given memfs.
+// Files which already have a gzip sidecar or which do not get smaller when compressed are skipped.
+func CompressFileSys(mfs *memfs.FS) error {
+	// collect files first as the memfs can not be written while walking it
+	var files []string
+	err := fs.WalkDir(mfs, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Join(ErrUnexpectedWalkError, err)
+		}
+		if d.IsDir() || !compressibleExts[strings.ToLower(path.Ext(p))] {
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range files {
+		if fileExists(mfs, p+".gz") {
+			continue
+		}
+
+		data, err := fs.ReadFile(mfs, p)
+		if err != nil {
+			return errors.Join(ErrCouldNotReadFile, err)
+		}
+
+		compressed, err := gzipData(data)
+		if err != nil {
+			return errors.Join(ErrCouldNotCompressFile, err)
+		}
+
+		// skip files which do not benefit from compression
+		if len(compressed) >= len(data) {
+			continue
+		}
+
+		if err := mfs.WriteFile(p+".gz", compressed, fs.ModeAppend); err != nil {
+			return errors.Join(ErrCouldNotWriteFile, err)
+		}
+	}
+
+	return nil
+}
+
+// gzipData compresses data with the best gzip compression level
+func gzipData(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&b, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,69 @@
+package spaserve
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestCompressFileSys(t *testing.T) {
+	content := []byte(strings.Repeat("console.log('hello world');\n", 32))
+
+	mfs := memfs.New()
+	_ = mfs.MkdirAll("assets", 0755)
+	_ = mfs.WriteFile("assets/app.js", content, 0644)
+	_ = mfs.WriteFile("assets/tiny.css", []byte("a{}"), 0644)
+	_ = mfs.WriteFile("assets/image.png", content, 0644)
+	_ = mfs.WriteFile("assets/existing.js", content, 0644)
+	_ = mfs.WriteFile("assets/existing.js.gz", []byte("existing"), 0644)
+
+	if err := CompressFileSys(mfs); err != nil {
+		t.Fatalf("CompressFileSys() returned an unexpected error: %v", err)
+	}
+
+	t.Run("compresses compressible file", func(t *testing.T) {
+		data, err := fs.ReadFile(mfs, "assets/app.js.gz")
+		if err != nil {
+			t.Fatalf("expected gzip sidecar, got error: %v", err)
+		}
+
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("gzip.NewReader() returned an unexpected error: %v", err)
+		}
+		got, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("io.ReadAll() returned an unexpected error: %v", err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("decompressed sidecar does not match original")
+		}
+	})
+
+	t.Run("skips files which do not get smaller", func(t *testing.T) {
+		if fileExists(mfs, "assets/tiny.css.gz") {
+			t.Error("expected no sidecar for tiny file")
+		}
+	})
+
+	t.Run("skips incompressible extensions", func(t *testing.T) {
+		if fileExists(mfs, "assets/image.png.gz") {
+			t.Error("expected no sidecar for png file")
+		}
+	})
+
+	t.Run("keeps existing sidecars", func(t *testing.T) {
+		data, err := fs.ReadFile(mfs, "assets/existing.js.gz")
+		if err != nil {
+			t.Fatalf("expected existing sidecar, got error: %v", err)
+		}
+		if string(data) != "existing" {
+			t.Errorf("expected existing sidecar to be kept, got %q", data)
+		}
+	})
+}
@@ -12,7 +12,26 @@ import (
 // The function should return the modified data and an error if one occurred.
 type OnHookFunc func(path string, data []byte) ([]byte, error)
 
+// chainHooks returns an OnHookFunc which runs the hooks in order
+func chainHooks(hooks ...OnHookFunc) OnHookFunc {
+	return func(path string, data []byte) ([]byte, error) {
+		var err error
+		for _, hook := range hooks {
+			if data, err = hook(path, data); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+}
+
 func CopyFileSys(filesys fs.FS, onHook OnHookFunc) (*memfs.FS, error) {
+	return copyFileSys(filesys, onHook, nil)
+}
+
+// copyFileSys copies the file system into a memfs like CopyFileSys, files for which skip returns true are not copied.
+// Files are visited in lexical order, so a file is always visited before its sidecars (e.g. app.js before app.js.gz).
+func copyFileSys(filesys fs.FS, onHook OnHookFunc, skip func(path string) bool) (*memfs.FS, error) {
 	mfs := memfs.New()
 	err := fs.WalkDir(filesys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -27,6 +46,11 @@ func CopyFileSys(filesys fs.FS, onHook OnHookFunc) (*memfs.FS, error) {
 			return nil
 		}
 
+		// skip file
+		if skip != nil && skip(path) {
+			return nil
+		}
+
 		// open file
 		f, err := filesys.Open(path)
 		if err != nil {
@@ -0,0 +1,158 @@
+package spaserve
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// CSPMode selects how inline scripts of entry documents are allowed by the Content-Security-Policy.
+type CSPMode int
+
+const (
+	// CSPModeHash computes the SHA-256 hashes of the inline scripts when the files are built
+	CSPModeHash CSPMode = iota
+	// CSPModeNonce stamps a fresh nonce onto the inline scripts of every entry document response
+	CSPModeNonce
+)
+
+// defaultCSPPolicy is used when no policy is provided
+const defaultCSPPolicy = "script-src 'self'"
+
+type cspOpts struct {
+	mode   CSPMode
+	policy string
+}
+
+// inlineScriptHashes returns the CSP hash sources of all inline scripts of the given html document
+func inlineScriptHashes(d []byte) ([]string, error) {
+	var hashes []string
+	z := html.NewTokenizer(bytes.NewReader(d))
+	inlineScript := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return hashes, nil
+			}
+			return nil, errors.Join(ErrCouldNotParseIndex, z.Err())
+		case html.StartTagToken:
+			tn, hasAttr := z.TagName()
+			inlineScript = string(tn) == "script" && !hasSrcAttr(z, hasAttr)
+		case html.TextToken:
+			if inlineScript {
+				sum := sha256.Sum256(z.Text())
+				hashes = append(hashes, "'sha256-"+base64.StdEncoding.EncodeToString(sum[:])+"'")
+			}
+			inlineScript = false
+		default:
+			inlineScript = false
+		}
+	}
+}
+
+// hasSrcAttr returns true if the current start tag of the tokenizer has a src attribute
+func hasSrcAttr(z *html.Tokenizer, hasAttr bool) bool {
+	for hasAttr {
+		var key []byte
+		key, _, hasAttr = z.TagAttr()
+		if string(key) == "src" {
+			return true
+		}
+	}
+	return false
+}
+
+// stampNonce sets the nonce attribute on all inline scripts of the given html document. The attribute is spliced in
+// directly after the tag name without touching any other byte, so it takes precedence over an existing nonce.
+func stampNonce(d []byte, nonce string) ([]byte, error) {
+	var (
+		b      bytes.Buffer
+		offset int
+		last   int
+	)
+	b.Grow(len(d) + 64)
+
+	z := html.NewTokenizer(bytes.NewReader(d))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if !errors.Is(z.Err(), io.EOF) {
+				return nil, errors.Join(ErrCouldNotParseIndex, z.Err())
+			}
+			break
+		}
+
+		start := offset
+		offset += len(z.Raw())
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		tn, hasAttr := z.TagName()
+		if string(tn) != "script" || hasSrcAttr(z, hasAttr) {
+			continue
+		}
+
+		// insert after "<script"
+		at := start + len("<script")
+		b.Write(d[last:at])
+		b.WriteString(` nonce="` + nonce + `"`)
+		last = at
+	}
+	b.Write(d[last:])
+	return b.Bytes(), nil
+}
+
+// newNonce returns a random base64 encoded nonce
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Join(ErrCouldNotGenerateNonce, err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// cspHeader adds the sources to the script-src directive of the policy.
+// If the policy has no script-src directive, one is added with the sources of default-src or 'self'.
+func cspHeader(policy string, sources []string) string {
+	if len(sources) == 0 {
+		return policy
+	}
+
+	var (
+		directives []string
+		fallback   = "'self'"
+		found      bool
+	)
+	for _, directive := range strings.Split(policy, ";") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+
+		name, value, _ := strings.Cut(directive, " ")
+		switch strings.ToLower(name) {
+		case "script-src":
+			found = true
+			directive = strings.TrimSpace(directive + " " + strings.Join(sources, " "))
+		case "default-src":
+			if value = strings.TrimSpace(value); value != "" {
+				fallback = value
+			}
+		}
+		directives = append(directives, directive)
+	}
+
+	if !found {
+		directives = append(directives, "script-src "+fallback+" "+strings.Join(sources, " "))
+	}
+
+	return strings.Join(directives, "; ")
+}
@@ -0,0 +1,162 @@
+package spaserve
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestCSPHeader(t *testing.T) {
+	sources := []string{"'sha256-abc'"}
+
+	tt := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{
+			name:   "extends script-src",
+			policy: "default-src 'self'; script-src 'self' https://cdn.example.com",
+			want:   "default-src 'self'; script-src 'self' https://cdn.example.com 'sha256-abc'",
+		},
+		{
+			name:   "adds script-src from default-src",
+			policy: "default-src 'self' https://example.com; img-src *",
+			want:   "default-src 'self' https://example.com; img-src *; script-src 'self' https://example.com 'sha256-abc'",
+		},
+		{
+			name:   "adds script-src with self",
+			policy: "img-src *;",
+			want:   "img-src *; script-src 'self' 'sha256-abc'",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cspHeader(tc.policy, sources); got != tc.want {
+				t.Errorf("cspHeader() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInlineScriptHashes(t *testing.T) {
+	script := `window.APP_ENV = {"name":"test"};`
+	sum := sha256.Sum256([]byte(script))
+	want := "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+
+	doc := `<html><head><script type="text/javascript">` + script + `</script><script src="/app.js"></script></head><body></body></html>`
+
+	got, err := inlineScriptHashes([]byte(doc))
+	if err != nil {
+		t.Fatalf("inlineScriptHashes() returned an unexpected error: %v", err)
+	}
+
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("inlineScriptHashes() = %v, want [%s]", got, want)
+	}
+}
+
+func TestStampNonce(t *testing.T) {
+	doc := `<html><head><script>inline()</script><script src="/app.js"></script></head><body></body></html>`
+
+	got, err := stampNonce([]byte(doc), "abc")
+	if err != nil {
+		t.Fatalf("stampNonce() returned an unexpected error: %v", err)
+	}
+
+	want := `<html><head><script nonce="abc">inline()</script><script src="/app.js"></script></head><body></body></html>`
+	if string(got) != want {
+		t.Errorf("stampNonce() = %s, want %s", got, want)
+	}
+
+	t.Run("preserves bytes", func(t *testing.T) {
+		doc := `<!doctype html><meta charset=utf-8><p>text<SCRIPT type='module'>inline()</SCRIPT>`
+
+		got, err := stampNonce([]byte(doc), "abc")
+		if err != nil {
+			t.Fatalf("stampNonce() returned an unexpected error: %v", err)
+		}
+
+		want := `<!doctype html><meta charset=utf-8><p>text<SCRIPT nonce="abc" type='module'>inline()</SCRIPT>`
+		if string(got) != want {
+			t.Errorf("stampNonce() = %s, want %s", got, want)
+		}
+	})
+}
+
+func TestContentSecurityPolicy(t *testing.T) {
+	filesys := memfs.New()
+	_ = filesys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+	_ = filesys.WriteFile("app.js", []byte("console.log('hello');"), 0644)
+
+	env := map[string]string{"name": "test"}
+
+	serve := func(handler http.Handler, p string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, p, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("hash mode", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(filesys, WithInjectWebEnv(env, ""), WithContentSecurityPolicy(CSPModeHash, ""))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		sum := sha256.Sum256([]byte(`window.APP_ENV = {"name":"test"};`))
+		want := "script-src 'self' 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+
+		for _, p := range []string{"/", "/some/route"} {
+			if got := serve(handler, p).Header().Get("Content-Security-Policy"); got != want {
+				t.Errorf("Expected Content-Security-Policy %q for %s, but got %q", want, p, got)
+			}
+		}
+
+		// Assert that assets do not get the policy
+		if got := serve(handler, "/app.js").Header().Get("Content-Security-Policy"); got != "" {
+			t.Errorf("Expected no Content-Security-Policy for assets, but got %q", got)
+		}
+	})
+
+	t.Run("nonce mode", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(filesys, WithInjectWebEnv(env, ""), WithContentSecurityPolicy(CSPModeNonce, "default-src 'self'"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		nonceRegex := regexp.MustCompile(`'nonce-([A-Za-z0-9+/=]+)'`)
+
+		var nonces []string
+		for i := 0; i < 2; i++ {
+			w := serve(handler, "/")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, but got %d", http.StatusOK, w.Code)
+			}
+
+			header := w.Header().Get("Content-Security-Policy")
+			m := nonceRegex.FindStringSubmatch(header)
+			if m == nil {
+				t.Fatalf("Expected nonce in Content-Security-Policy, but got %q", header)
+			}
+
+			// Assert that the injected script carries the nonce
+			if !strings.Contains(w.Body.String(), `nonce="`+m[1]+`"`) {
+				t.Errorf("Expected injected script to carry nonce %q, but got %q", m[1], w.Body.String())
+			}
+			nonces = append(nonces, m[1])
+		}
+
+		// Assert that every response gets a fresh nonce
+		if nonces[0] == nonces[1] {
+			t.Errorf("Expected a fresh nonce per response, but got %q twice", nonces[0])
+		}
+	})
+}
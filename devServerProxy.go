@@ -0,0 +1,128 @@
+package spaserve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// inboundRequestKey is the context key of the inbound request of a proxied request
+type inboundRequestKey struct{}
+
+// newDevServerProxy creates a reverse proxy to the dev server which injects the web env into proxied html documents
+func newDevServerProxy(upstream string, opts staticFilesHandlerOpts, logger *servespaLogger, muxErrHandler func(int, http.ResponseWriter, *http.Request)) (*httputil.ReverseProxy, error) {
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return nil, errors.Join(ErrCouldNotParseUpstream, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, ErrCouldNotParseUpstream
+	}
+
+	// construct the nodes once so errors surface at startup
+	envs, err := prepareWebEnvs(opts.webEnvs, opts.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			// keep the inbound request for the web env provider as the outbound host is rewritten
+			pr.Out = pr.Out.WithContext(context.WithValue(pr.Out.Context(), inboundRequestKey{}, pr.In))
+			// let the transport negotiate compression so html bodies arrive decoded
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if (len(envs) == 0 && opts.webEnvProvider == nil) || !isHTMLResponse(resp) {
+				return nil
+			}
+
+			data, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return errors.Join(ErrCouldNotReadFile, err)
+			}
+
+			data, err = injectWebEnvs(envs, data)
+			if err != nil {
+				return err
+			}
+
+			// inject the web env of the provider for the inbound request, dev documents change too often to cache
+			if opts.webEnvProvider != nil {
+				in, ok := resp.Request.Context().Value(inboundRequestKey{}).(*http.Request)
+				if !ok {
+					in = resp.Request
+				}
+				_, env, err := opts.webEnvProvider(in)
+				if err != nil {
+					return errors.Join(ErrCouldNotProvideWebEnv, err)
+				}
+				scriptTag, err := constructProvidedScriptTag(opts.webEnvProviderNs, env)
+				if err != nil {
+					return err
+				}
+				if data, err = appendToHead([]*html.Node{scriptTag}, data); err != nil {
+					return err
+				}
+			}
+
+			resp.Body = io.NopCloser(bytes.NewReader(data))
+			resp.ContentLength = int64(len(data))
+			resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+			resp.Header.Del("Content-Encoding")
+			resp.Header.Del("ETag")
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.logContext(r.Context(), slog.LevelError, "could not proxy request", slog.Attr{Key: "error", Value: slog.AnyValue(err)})
+			muxErrHandler(http.StatusBadGateway, w, r)
+		},
+	}, nil
+}
+
+// serveDevServerProxy proxies the request with the base path trimmed, navigation requests fall back to the entry
+// document or the document of the first matching fallback rule
+func (h *StaticFilesHandler) serveDevServerProxy(w http.ResponseWriter, r *http.Request, cleanedPath string) {
+	if isNavigationRequest(r, cleanedPath) {
+		document := h.fallbackDocument(cleanedPath)
+		h.logger.logContext(r.Context(), slog.LevelDebug, "proxy navigation, serve fallback", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)}, slog.Attr{Key: "document", Value: slog.StringValue(document)})
+		r.URL.Path = fileURLPath(document)
+	}
+	r.URL.RawPath = ""
+
+	h.proxy.ServeHTTP(w, r)
+}
+
+// isNavigationRequest returns true for GET requests of a document without a file extension
+func isNavigationRequest(r *http.Request, cleanedPath string) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if path.Ext(cleanedPath) != "" {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// isHTMLResponse returns true for successful responses with an html content type
+func isHTMLResponse(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/html"
+}
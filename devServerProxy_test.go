@@ -0,0 +1,190 @@
+package spaserve
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDevServerProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream-Path", r.URL.Path)
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = io.WriteString(w, "<html><head></head><body>dev</body></html>")
+		case "/src/main.ts":
+			w.Header().Set("Content-Type", "text/javascript")
+			_, _ = io.WriteString(w, "console.log('dev');")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	env := struct {
+		Name string `json:"name"`
+	}{
+		Name: "proxied",
+	}
+
+	handler, err := NewStaticFilesHandler(nil,
+		WithDevServerProxy(upstream.URL),
+		WithBasePath("/app"),
+		WithInjectWebEnv(env, ""),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name             string
+		path             string
+		accept           string
+		wantCode         int
+		wantUpstreamPath string
+		wantEnv          bool
+	}{
+		{
+			name:             "injects env into index",
+			path:             "/app/",
+			accept:           "text/html",
+			wantCode:         http.StatusOK,
+			wantUpstreamPath: "/",
+			wantEnv:          true,
+		},
+		{
+			name:             "falls back to index on navigation",
+			path:             "/app/some/route",
+			accept:           "text/html,application/xhtml+xml",
+			wantCode:         http.StatusOK,
+			wantUpstreamPath: "/",
+			wantEnv:          true,
+		},
+		{
+			name:             "proxies assets with base path trimmed",
+			path:             "/app/src/main.ts",
+			accept:           "*/*",
+			wantCode:         http.StatusOK,
+			wantUpstreamPath: "/src/main.ts",
+			wantEnv:          false,
+		},
+		{
+			name:             "proxies non navigation requests as is",
+			path:             "/app/@vite/client",
+			accept:           "*/*",
+			wantCode:         http.StatusNotFound,
+			wantUpstreamPath: "/@vite/client",
+			wantEnv:          false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Accept", tc.accept)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("Expected status code %d, but got %d", tc.wantCode, w.Code)
+			}
+
+			if got := w.Header().Get("X-Upstream-Path"); got != tc.wantUpstreamPath {
+				t.Errorf("Expected upstream path %q, but got %q", tc.wantUpstreamPath, got)
+			}
+
+			if got := strings.Contains(w.Body.String(), "window.APP_ENV"); got != tc.wantEnv {
+				t.Errorf("Expected web env injected to be %v, but got %q", tc.wantEnv, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("falls back to the entry and fallback documents on navigation", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(nil,
+			WithDevServerProxy(upstream.URL),
+			WithEntryDocument("app.html"),
+			WithFallback("/admin/**", "admin/index.html"),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		tt := []struct {
+			path             string
+			wantUpstreamPath string
+		}{
+			{path: "/some/route", wantUpstreamPath: "/app.html"},
+			{path: "/admin/x", wantUpstreamPath: "/admin/"},
+		}
+
+		for _, tc := range tt {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Accept", "text/html")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if got := w.Header().Get("X-Upstream-Path"); got != tc.wantUpstreamPath {
+				t.Errorf("Expected upstream path %q for %q, but got %q", tc.wantUpstreamPath, tc.path, got)
+			}
+		}
+	})
+
+	t.Run("injects env of provider", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(nil,
+			WithDevServerProxy(upstream.URL),
+			WithInjectWebEnv(env, ""),
+			WithWebEnvProvider(func(r *http.Request) (string, any, error) {
+				return r.Host, map[string]string{"host": r.Host}, nil
+			}, "TENANT_ENV"),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = "acme.example.com"
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		for _, want := range []string{`window.TENANT_ENV = {"host":"acme.example.com"};`, "window.APP_ENV"} {
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("Expected body to contain %q, but got %q", want, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("returns bad gateway when upstream is down", func(t *testing.T) {
+		down := httptest.NewServer(http.NotFoundHandler())
+		down.Close()
+
+		handler, err := NewStaticFilesHandler(nil, WithDevServerProxy(down.URL))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("Expected status code %d, but got %d", http.StatusBadGateway, w.Code)
+		}
+	})
+
+	t.Run("rejects invalid upstream", func(t *testing.T) {
+		if _, err := NewStaticFilesHandler(nil, WithDevServerProxy("localhost:5173")); !errors.Is(err, ErrCouldNotParseUpstream) {
+			t.Errorf("Expected ErrCouldNotParseUpstream, but got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid namespace", func(t *testing.T) {
+		if _, err := NewStaticFilesHandler(nil, WithDevServerProxy(upstream.URL), WithInjectWebEnv(env, "!!!")); !errors.Is(err, ErrCouldNotParseNamespace) {
+			t.Errorf("Expected ErrCouldNotParseNamespace, but got %v", err)
+		}
+	})
+}
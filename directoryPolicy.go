@@ -0,0 +1,82 @@
+package spaserve
+
+import (
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// DirectoryPolicy selects how requests of a directory without an index.html are answered.
+type DirectoryPolicy int
+
+const (
+	// DirectoryPolicyFallback serves the fallback document and lets the SPA handle the route
+	DirectoryPolicyFallback DirectoryPolicy = iota
+	// DirectoryPolicyNotFound responds with 404 Not Found
+	DirectoryPolicyNotFound
+	// DirectoryPolicyForbidden responds with 403 Forbidden
+	DirectoryPolicyForbidden
+	// DirectoryPolicyListing renders a listing of the directory, only opt in for internal tools as it exposes
+	// every file of the directory
+	DirectoryPolicyListing
+)
+
+// serveDirectory answers the request of a directory without an index.html according to the directory policy
+func (h *StaticFilesHandler) serveDirectory(w http.ResponseWriter, r *http.Request, tree *staticFilesTree, cleanedPath string) {
+	ctx := r.Context()
+
+	switch h.opts.directoryPolicy {
+	case DirectoryPolicyNotFound:
+		h.logger.logContext(ctx, slog.LevelDebug, "not found, directory", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
+		h.muxErrHandler(http.StatusNotFound, w, r)
+	case DirectoryPolicyForbidden:
+		h.logger.logContext(ctx, slog.LevelDebug, "forbidden, directory", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
+		h.muxErrHandler(http.StatusForbidden, w, r)
+	case DirectoryPolicyListing:
+		h.logger.logContext(ctx, slog.LevelDebug, "serve directory listing", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
+		// the file server redirects directory requests without a trailing slash, which the cleaned path never has
+		r.URL.Path = strings.TrimSuffix("/"+cleanedPath, "/") + "/"
+		tree.fileServer.ServeHTTP(w, r)
+	default:
+		document := h.fallbackDocument(cleanedPath)
+		h.logger.logContext(ctx, slog.LevelDebug, "directory, serve fallback", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)}, slog.Attr{Key: "document", Value: slog.StringValue(document)})
+		h.serveFile(w, r, tree, document, true)
+	}
+}
+
+// noListingFileSystem is an http.FileSystem whose directories can not be read, so the file server never renders a
+// directory listing even if a request slips past the directory policy
+type noListingFileSystem struct {
+	http.FileSystem
+}
+
+func (fsys noListingFileSystem) Open(name string) (http.File, error) {
+	f, err := fsys.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return noListingFile{File: f}, nil
+}
+
+// noListingFile is an http.File whose directory entries can not be read
+type noListingFile struct {
+	http.File
+}
+
+func (noListingFile) Readdir(int) ([]fs.FileInfo, error) {
+	return nil, fs.ErrPermission
+}
+
+func (noListingFile) ReadDir(int) ([]fs.DirEntry, error) {
+	return nil, fs.ErrPermission
+}
+
+// newFileServer returns the file server of the file system, which renders directory listings only if the directory
+// policy opts in
+func newFileServer(filesys fs.FS, policy DirectoryPolicy) http.Handler {
+	if policy == DirectoryPolicyListing {
+		return http.FileServer(http.FS(filesys))
+	}
+	return http.FileServer(noListingFileSystem{FileSystem: http.FS(filesys)})
+}
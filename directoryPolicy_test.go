@@ -0,0 +1,139 @@
+package spaserve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestStaticFilesHandlerDirectoryPolicy(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.MkdirAll("assets", 0755)
+	_ = fsys.MkdirAll("about", 0755)
+	_ = fsys.WriteFile("index.html", []byte("<html><head></head><body>app</body></html>"), 0644)
+	_ = fsys.WriteFile("assets/app.js", []byte("console.log('app');"), 0644)
+	_ = fsys.WriteFile("about/index.html", []byte("<html><head></head><body>about</body></html>"), 0644)
+
+	tt := []struct {
+		name       string
+		fn         []staticFilesHandlerFunc
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "fallback by default",
+			path:       "/assets",
+			wantStatus: http.StatusOK,
+			wantBody:   "<body>app</body>",
+		},
+		{
+			name:       "fallback with trailing slash",
+			path:       "/assets/",
+			wantStatus: http.StatusOK,
+			wantBody:   "<body>app</body>",
+		},
+		{
+			name:       "not found",
+			fn:         []staticFilesHandlerFunc{WithDirectoryPolicy(DirectoryPolicyNotFound)},
+			path:       "/assets",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "forbidden",
+			fn:         []staticFilesHandlerFunc{WithDirectoryPolicy(DirectoryPolicyForbidden)},
+			path:       "/assets/",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "listing opt in",
+			fn:         []staticFilesHandlerFunc{WithDirectoryPolicy(DirectoryPolicyListing)},
+			path:       "/assets",
+			wantStatus: http.StatusOK,
+			wantBody:   `<a href="app.js">app.js</a>`,
+		},
+		{
+			name:       "directories with an index.html are not affected",
+			fn:         []staticFilesHandlerFunc{WithDirectoryPolicy(DirectoryPolicyForbidden)},
+			path:       "/about",
+			wantStatus: http.StatusOK,
+			wantBody:   "<body>about</body>",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, err := NewStaticFilesHandler(fsys, tc.fn...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, but got %d", tc.wantStatus, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("Expected body to contain %q, but got %q", tc.wantBody, w.Body.String())
+			}
+			if tc.fn == nil && strings.Contains(w.Body.String(), "app.js") {
+				t.Errorf("Expected no directory listing, but got %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestStaticFilesHandlerWithoutRootIndex(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.MkdirAll("assets", 0755)
+	_ = fsys.WriteFile("assets/app.js", []byte("console.log('app');"), 0644)
+	_ = fsys.WriteFile("secret-config.json", []byte(`{"a":1}`), 0644)
+
+	tt := []struct {
+		name       string
+		fn         []staticFilesHandlerFunc
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "root", path: "/", wantStatus: http.StatusNotFound},
+		{name: "undefined route", path: "/some/route", wantStatus: http.StatusNotFound},
+		{name: "directory", path: "/assets", wantStatus: http.StatusNotFound},
+		{name: "forbidden root", fn: []staticFilesHandlerFunc{WithDirectoryPolicy(DirectoryPolicyForbidden)}, path: "/", wantStatus: http.StatusForbidden},
+		{name: "listing opt in", fn: []staticFilesHandlerFunc{WithDirectoryPolicy(DirectoryPolicyListing)}, path: "/", wantStatus: http.StatusOK, wantBody: "secret-config.json"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, err := NewStaticFilesHandler(fsys, tc.fn...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			if w.Code != tc.wantStatus {
+				t.Errorf("Expected status code %d, but got %d", tc.wantStatus, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("Expected body to contain %q, but got %q", tc.wantBody, w.Body.String())
+			}
+			if tc.wantBody == "" && strings.Contains(w.Body.String(), "secret-config.json") {
+				t.Errorf("Expected no directory listing, but got %q", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("file server never lists directories", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		newFileServer(fsys, DirectoryPolicyFallback).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if strings.Contains(w.Body.String(), "secret-config.json") {
+			t.Errorf("Expected no directory listing, but got %q", w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package spaserve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// envKeyExts are the file extensions of bundles scanned for web env references
+var envKeyExts = map[string]bool{
+	".js":  true,
+	".mjs": true,
+	".cjs": true,
+}
+
+// EnvKeyReport lists the keys of a web env namespace which bundles reference but the web env lacks, and the keys
+// which no bundle references.
+type EnvKeyReport struct {
+	Namespace string
+	// Missing are the keys referenced by bundles which are not in the web env, they are undefined at runtime
+	Missing []string
+	// Unused are the keys of the web env which are not referenced by any bundle. References through an alias
+	// (e.g. const env = window.APP_ENV) are not found, so unused keys are only a hint.
+	Unused []string
+}
+
+// EnvKeyAnalyzer scans js bundles for references of the top-level keys of a web env namespace
+// (e.g. window.APP_ENV.apiUrl, APP_ENV?.apiUrl or APP_ENV["apiUrl"]) and compares them with the keys of the web env.
+// Use Hook as the OnHookFunc of CopyFileSys and Report once all files are copied.
+type EnvKeyAnalyzer struct {
+	ns    string
+	regex *regexp.Regexp
+	keys  map[string]bool
+
+	mu         sync.Mutex
+	referenced map[string]bool
+}
+
+// NewEnvKeyAnalyzer creates an analyzer for the top-level keys of the config.
+//   - conf: the web environment, use json struct tags to drive the marshalling
+//   - ns: the namespace of the web environment
+func NewEnvKeyAnalyzer(conf any, ns string) (*EnvKeyAnalyzer, error) {
+	ns, err := validateNamespace(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, ErrConfigNotAnObject
+	}
+
+	keys := map[string]bool{}
+	for key := range fields {
+		keys[key] = true
+	}
+
+	// the namespace must not be part of a longer identifier or property path (e.g. MY_APP_ENV or foo.APP_ENV),
+	// unless it is a property of window, globalThis or self
+	segments := strings.Split(ns, ".")
+	for i, segment := range segments {
+		segments[i] = regexp.QuoteMeta(segment)
+	}
+	nsPattern := `(?:(?:window|globalThis|self)\s*\.\s*|^|[^\w$.])` + strings.Join(segments, `\s*\.\s*`) + `\s*`
+	regex := regexp.MustCompile(nsPattern + `(?:\??\.\s*([A-Za-z_$][\w$]*)|(?:\?\.)?\[\s*(?:"([^"]*)"|'([^']*)'|` + "`([^`$]*)`" + `)\s*\])`)
+
+	return &EnvKeyAnalyzer{
+		ns:         ns,
+		regex:      regex,
+		keys:       keys,
+		referenced: map[string]bool{},
+	}, nil
+}
+
+// Hook records the keys referenced by the file, it can be used as OnHookFunc and never changes the data.
+func (a *EnvKeyAnalyzer) Hook(name string, data []byte) ([]byte, error) {
+	if !envKeyExts[strings.ToLower(path.Ext(name))] {
+		return data, nil
+	}
+
+	matches := a.regex.FindAllSubmatch(data, -1)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, m := range matches {
+		for _, key := range m[1:] {
+			if len(key) > 0 {
+				a.referenced[string(key)] = true
+			}
+		}
+	}
+	return data, nil
+}
+
+// Report returns the keys referenced so far which are missing from the web env and the unreferenced keys.
+func (a *EnvKeyAnalyzer) Report() EnvKeyReport {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	report := EnvKeyReport{Namespace: a.ns}
+	for key := range a.referenced {
+		if !a.keys[key] {
+			report.Missing = append(report.Missing, key)
+		}
+	}
+	for key := range a.keys {
+		if !a.referenced[key] {
+			report.Unused = append(report.Unused, key)
+		}
+	}
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unused)
+	return report
+}
+
+// missingEnvKeys returns an error listing the missing keys of all reports, or nil if no key is missing
+func missingEnvKeys(reports []EnvKeyReport) error {
+	var missing []string
+	for _, report := range reports {
+		for _, key := range report.Missing {
+			missing = append(missing, report.Namespace+"."+key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return errors.Join(ErrMissingEnvKeys, errors.New(strings.Join(missing, ", ")))
+}
+
+// logEnvKeyReports logs a warning for every missing and unused key
+func logEnvKeyReports(logger *servespaLogger, reports []EnvKeyReport) {
+	ctx := context.Background()
+	for _, report := range reports {
+		for _, key := range report.Missing {
+			logger.logContext(ctx, slog.LevelWarn, "web env key is referenced by a bundle but missing",
+				slog.Attr{Key: "namespace", Value: slog.StringValue(report.Namespace)},
+				slog.Attr{Key: "key", Value: slog.StringValue(key)},
+			)
+		}
+		for _, key := range report.Unused {
+			logger.logContext(ctx, slog.LevelWarn, "web env key is not referenced by any bundle",
+				slog.Attr{Key: "namespace", Value: slog.StringValue(report.Namespace)},
+				slog.Attr{Key: "key", Value: slog.StringValue(key)},
+			)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestEnvKeyAnalyzer(t *testing.T) {
+	conf := map[string]any{"apiUrl": "/api", "retries": 3, "theme": "dark", "unused": true}
+
+	tt := []struct {
+		name  string
+		ns    string
+		files map[string]string
+		want  EnvKeyReport
+	}{
+		{
+			name: "property and bracket references",
+			ns:   "APP_ENV",
+			files: map[string]string{
+				"assets/app.js":    `fetch(window.APP_ENV.apiUrl);const r=APP_ENV?.retries;`,
+				"assets/chunk.mjs": `const t = globalThis.APP_ENV["theme"], s = APP_ENV['sentryDsn'], c = APP_ENV[` + "`cdn`" + `];`,
+			},
+			want: EnvKeyReport{Namespace: "APP_ENV", Missing: []string{"cdn", "sentryDsn"}, Unused: []string{"unused"}},
+		},
+		{
+			name: "longer identifiers and other files are ignored",
+			ns:   "APP_ENV",
+			files: map[string]string{
+				"assets/app.js":     `MY_APP_ENV.foo; APP_ENV_X.bar; other.APP_ENV.baz; APP_ENV.apiUrl`,
+				"assets/app.js.map": `APP_ENV.mapped`,
+				"index.html":        `<script>APP_ENV.inline</script>`,
+			},
+			want: EnvKeyReport{Namespace: "APP_ENV", Unused: []string{"retries", "theme", "unused"}},
+		},
+		{
+			name: "dotted namespace",
+			ns:   "__SHELL__.apps.billing",
+			files: map[string]string{
+				"assets/app.js": `window.__SHELL__.apps.billing.apiUrl; __SHELL__.apps.billing.missing; __SHELL__.apps.other.retries`,
+			},
+			want: EnvKeyReport{Namespace: "__SHELL__.apps.billing", Missing: []string{"missing"}, Unused: []string{"retries", "theme", "unused"}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			analyzer, err := NewEnvKeyAnalyzer(conf, tc.ns)
+			if err != nil {
+				t.Fatalf("NewEnvKeyAnalyzer() returned an unexpected error: %v", err)
+			}
+
+			for name, data := range tc.files {
+				got, err := analyzer.Hook(name, []byte(data))
+				if err != nil {
+					t.Fatalf("Hook() returned an unexpected error: %v", err)
+				}
+				if string(got) != data {
+					t.Errorf("Hook() changed the data of %s", name)
+				}
+			}
+
+			if got := analyzer.Report(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Report() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("rejects non object config", func(t *testing.T) {
+		if _, err := NewEnvKeyAnalyzer([]string{"a"}, "APP_ENV"); !errors.Is(err, ErrConfigNotAnObject) {
+			t.Errorf("NewEnvKeyAnalyzer() error = %v, want %v", err, ErrConfigNotAnObject)
+		}
+	})
+}
+
+func TestStaticFilesHandlerWithEnvKeyAnalysis(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+	_ = fsys.WriteFile("app.js", []byte(`fetch(window.APP_ENV.apiUrl + FLAGS.beta);`), 0644)
+
+	env := map[string]string{"apiUrl": "/api", "title": "app"}
+	flags := map[string]bool{"beta": true}
+
+	t.Run("reports and logs keys", func(t *testing.T) {
+		logs := new(bytes.Buffer)
+		handler, err := NewStaticFilesHandler(fsys,
+			WithLogger(slog.New(slog.NewJSONHandler(logs, nil))),
+			WithInjectWebEnv(env, "APP_ENV"),
+			WithInjectWebEnv(flags, "FLAGS"),
+			WithEnvKeyAnalysis(false),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := []EnvKeyReport{
+			{Namespace: "APP_ENV", Unused: []string{"title"}},
+			{Namespace: "FLAGS"},
+		}
+		if got := handler.EnvKeyReports(); !reflect.DeepEqual(got, want) {
+			t.Errorf("EnvKeyReports() = %+v, want %+v", got, want)
+		}
+		if !strings.Contains(logs.String(), `"key":"title"`) {
+			t.Errorf("Expected unused key to be logged, but got %q", logs.String())
+		}
+	})
+
+	t.Run("strict mode fails on missing keys", func(t *testing.T) {
+		_, err := NewStaticFilesHandler(fsys,
+			WithInjectWebEnv(map[string]string{"title": "app"}, "APP_ENV"),
+			WithEnvKeyAnalysis(true),
+		)
+		if !errors.Is(err, ErrMissingEnvKeys) || !strings.Contains(err.Error(), "APP_ENV.apiUrl") {
+			t.Errorf("Expected error %v naming APP_ENV.apiUrl, but got %v", ErrMissingEnvKeys, err)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(fsys, WithInjectWebEnv(env, "APP_ENV"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := handler.EnvKeyReports(); got != nil {
+			t.Errorf("EnvKeyReports() = %+v, want nil", got)
+		}
+	})
+}
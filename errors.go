@@ -4,18 +4,75 @@ import "errors"
 
 // injectWebEnv.InjectWindowVars
 var ErrCouldNotMarshalConfig = errors.New("could not marshal config")
-var ErrNoIndexFound = errors.New("no index.html found")
+var ErrNoIndexFound = errors.New("no index.html or html document to inject into found")
 var ErrUnexpectedWalkError = errors.New("unexpected walk error")
 var ErrCouldNotOpenFile = errors.New("could not open file")
 var ErrCouldNotReadFile = errors.New("could not read file")
+
+// Deprecated: ErrCouldNotAppendToIndex is no longer returned, injection errors wrap ErrCouldNotParseIndex or
+// ErrCouldNotWriteIndex.
 var ErrCouldNotAppendToIndex = errors.New("could not append to index")
+
 var ErrCouldNotMakeDir = errors.New("could not make dir")
 var ErrCouldNotWriteFile = errors.New("could not write file")
-var ErrCouldNotParseNamespace = errors.New("namespace must match regex: ^[a-zA-Z_][a-zA-Z0-9_]*$")
+var ErrCouldNotParseNamespace = errors.New("namespace must be a dotted path of segments matching regex: ^[a-zA-Z_][a-zA-Z0-9_]*$")
+var ErrReservedNamespace = errors.New("namespace segment must not be a reserved word")
 var ErrNoNamespace = errors.New("no namespace provided")
+var ErrConfigNotAnObject = errors.New("config must marshal to a JSON object")
+var ErrDuplicateNamespace = errors.New("duplicate web env namespace")
 
 // injectWebEnv.appendToIndex
 var ErrCouldNotParseIndex = errors.New("could not parse index")
 var ErrCouldNotFindHead = errors.New("could not find <head> tag")
+
+// Deprecated: ErrCouldNotAppendScript is no longer returned, injection errors wrap ErrCouldNotParseIndex or
+// ErrCouldNotWriteIndex.
 var ErrCouldNotAppendScript = errors.New("could not append script")
+
 var ErrCouldNotWriteIndex = errors.New("could not write index")
+
+// injectPosition.injectAt
+var ErrCouldNotFindAnchor = errors.New("could not find injection anchor")
+var ErrCouldNotParseSelector = errors.New("selector must be of the form tag, [attr], [attr=value] or tag[attr=value]")
+
+// secrets.checkSecrets
+var ErrSecretField = errors.New("web env must not contain secret field")
+
+// jsonSchema.validateWebEnvSchema
+var ErrCouldNotParseSchema = errors.New("could not parse JSON schema")
+var ErrSchemaViolation = errors.New("web env does not match JSON schema")
+
+// typescript.GenerateTypeScript
+var ErrCouldNotGenerateTypes = errors.New("could not generate typescript declaration")
+
+// envKeys.missingEnvKeys
+var ErrMissingEnvKeys = errors.New("bundles reference web env keys which are missing")
+
+// compressFilesys.CompressFileSys
+var ErrCouldNotCompressFile = errors.New("could not compress file")
+
+// devServerProxy.newDevServerProxy
+var ErrCouldNotParseUpstream = errors.New("could not parse dev server upstream url")
+
+// staticFileServerHandler.Reload
+var ErrReloadDevServerProxy = errors.New("could not reload files of a dev server proxy")
+
+// multiAppHandler.NewMultiAppHandler
+var ErrNoApps = errors.New("no apps provided")
+var ErrDuplicateBasePath = errors.New("duplicate app base path")
+var ErrCouldNotCreateApp = errors.New("could not create app")
+
+// csp.newNonce
+var ErrCouldNotGenerateNonce = errors.New("could not generate nonce")
+
+// renderedDocument.renderVariant
+var ErrCouldNotProvideWebEnv = errors.New("could not provide web env")
+
+// webEnvEndpoint.renderWebEnvEndpoints
+var ErrDuplicateWebEnvEndpoint = errors.New("duplicate web env endpoint path")
+
+// osWebEnv.WebEnvFromOS
+var ErrDuplicateWebEnvKey = errors.New("duplicate web env key")
+
+// placeholders.NewPlaceholderReplacer
+var ErrDuplicatePlaceholder = errors.New("duplicate placeholder")
@@ -0,0 +1,40 @@
+package spaserve
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+)
+
+// etagLength is the number of hex characters of the SHA-256 digest used for an ETag
+const etagLength = 32
+
+// ComputeETags returns a strong ETag for every file of the given file system keyed by its path.
+// The ETag is derived from the file content only, so identical trees produce identical validators.
+func ComputeETags(filesys fs.FS) (map[string]string, error) {
+	etags := map[string]string{}
+	err := fs.WalkDir(filesys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Join(ErrUnexpectedWalkError, err)
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		data, err := fs.ReadFile(filesys, p)
+		if err != nil {
+			return errors.Join(ErrCouldNotReadFile, err)
+		}
+
+		etags[p] = contentETag(data)
+		return nil
+	})
+	return etags, err
+}
+
+// contentETag returns a strong ETag for the given content
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:])[:etagLength] + `"`
+}
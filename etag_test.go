@@ -0,0 +1,46 @@
+package spaserve
+
+import (
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestComputeETags(t *testing.T) {
+	newFS := func(content string) *memfs.FS {
+		mfs := memfs.New()
+		_ = mfs.MkdirAll("assets", 0755)
+		_ = mfs.WriteFile("index.html", []byte("<html></html>"), 0644)
+		_ = mfs.WriteFile("assets/app.js", []byte(content), 0644)
+		return mfs
+	}
+
+	a, err := ComputeETags(newFS("console.log(1);"))
+	if err != nil {
+		t.Fatalf("ComputeETags() returned an unexpected error: %v", err)
+	}
+	b, err := ComputeETags(newFS("console.log(1);"))
+	if err != nil {
+		t.Fatalf("ComputeETags() returned an unexpected error: %v", err)
+	}
+	c, err := ComputeETags(newFS("console.log(2);"))
+	if err != nil {
+		t.Fatalf("ComputeETags() returned an unexpected error: %v", err)
+	}
+
+	if len(a) != 2 {
+		t.Errorf("ComputeETags() returned %d etags, want 2", len(a))
+	}
+
+	if a["assets/app.js"] != b["assets/app.js"] {
+		t.Errorf("expected identical content to produce identical etags, got %s and %s", a["assets/app.js"], b["assets/app.js"])
+	}
+
+	if a["assets/app.js"] == c["assets/app.js"] {
+		t.Errorf("expected different content to produce different etags, got %s", a["assets/app.js"])
+	}
+
+	if got := a["index.html"]; len(got) != etagLength+2 || got[0] != '"' || got[len(got)-1] != '"' {
+		t.Errorf("expected quoted strong etag, got %s", got)
+	}
+}
@@ -0,0 +1,32 @@
+package spaserve
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tt := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.js", name: "app.js", want: true},
+		{pattern: "*.js", name: "assets/app.js", want: true},
+		{pattern: "*.js", name: "app.css", want: false},
+		{pattern: "assets/*", name: "assets/app.js", want: true},
+		{pattern: "assets/*", name: "assets/js/app.js", want: false},
+		{pattern: "assets/**", name: "assets/js/app.js", want: true},
+		{pattern: "/assets/**", name: "assets/app.js", want: true},
+		{pattern: "**/*.map", name: "assets/js/app.js.map", want: true},
+		{pattern: "**/*.map", name: "app.js.map", want: true},
+		{pattern: "**", name: "anything/at/all", want: true},
+		{pattern: "admin/index.html", name: "admin/index.html", want: true},
+		{pattern: "admin/index.html", name: "index.html", want: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			if got := matchGlob(tc.pattern, tc.name); got != tc.want {
+				t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package spaserve
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"golang.org/x/net/html"
+)
+
+// InjectFormat is the format the web environment is injected into html documents in.
+type InjectFormat int
+
+const (
+	// InjectFormatScript assigns the env to window in a script (e.g. <script>window.APP_ENV = {...};</script>)
+	InjectFormatScript InjectFormat = iota
+	// InjectFormatJSON embeds the env as a data island which is not executed
+	// (e.g. <script type="application/json" id="APP_ENV">{...}</script>)
+	InjectFormatJSON
+	// InjectFormatMeta adds one meta tag per top-level field (e.g. <meta name="APP_ENV:key" content="value">),
+	// string values are unquoted and other values are JSON
+	InjectFormatMeta
+	// InjectFormatFrozen assigns a deeply frozen env to globalThis so it can not be mutated at runtime
+	// (e.g. <script>globalThis.APP_ENV = deepFreeze({...});</script>)
+	InjectFormatFrozen
+)
+
+// deepFreezeFunc is a javascript function expression which freezes an object and all nested objects
+const deepFreezeFunc = `(function f(o){Object.values(o).forEach(function(v){if(v&&typeof v==="object")f(v)});return Object.freeze(o)})`
+
+// constructEnvNodes constructs the html nodes injecting the configuration in the given format
+func constructEnvNodes(ns string, conf any, format InjectFormat) ([]*html.Node, error) {
+	switch format {
+	case InjectFormatJSON:
+		b, err := json.Marshal(conf)
+		if err != nil {
+			return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+		}
+		return []*html.Node{newScriptNode(string(b), html.Attribute{Key: "type", Val: "application/json"}, html.Attribute{Key: "id", Val: ns})}, nil
+	case InjectFormatMeta:
+		return constructMetaTags(ns, conf)
+	case InjectFormatFrozen:
+		b, err := json.Marshal(conf)
+		if err != nil {
+			return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+		}
+		return []*html.Node{newScriptNode(namespaceAssignment("globalThis", ns, deepFreezeFunc+"("+string(b)+")"), html.Attribute{Key: "type", Val: "text/javascript"})}, nil
+	default:
+		scriptTag, err := constructScriptTag(ns, conf)
+		if err != nil {
+			return nil, err
+		}
+		return []*html.Node{scriptTag}, nil
+	}
+}
+
+// constructMetaTags constructs one meta tag per top-level field of the configuration in field order
+func constructMetaTags(ns string, conf any) ([]*html.Node, error) {
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, ErrConfigNotAnObject
+	}
+
+	var nodes []*html.Node
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+		}
+		key, ok := t.(string)
+		if !ok {
+			// end of object
+			break
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+		}
+
+		content := string(raw)
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			content = str
+		}
+
+		nodes = append(nodes, &html.Node{
+			Type: html.ElementNode,
+			Data: "meta",
+			Attr: []html.Attribute{{Key: "name", Val: ns + ":" + key}, {Key: "content", Val: content}},
+		})
+	}
+	return nodes, nil
+}
+
+// newScriptNode returns a script element with the given text and attributes
+func newScriptNode(text string, attr ...html.Attribute) *html.Node {
+	return &html.Node{
+		Type: html.ElementNode,
+		Data: "script",
+		Attr: attr,
+		FirstChild: &html.Node{
+			Type: html.TextNode,
+			Data: text,
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+	"golang.org/x/net/html"
+)
+
+func TestConstructEnvNodes(t *testing.T) {
+	conf := struct {
+		Foo    string         `json:"foo"`
+		Bar    int            `json:"bar"`
+		Nested map[string]int `json:"nested"`
+	}{
+		Foo:    "hello <world>",
+		Bar:    42,
+		Nested: map[string]int{"a": 1},
+	}
+
+	ns := "myNamespace"
+
+	tt := []struct {
+		name   string
+		format InjectFormat
+		want   string
+	}{
+		{
+			name:   "script",
+			format: InjectFormatScript,
+			want:   `<script type="text/javascript">window.myNamespace = {"foo":"hello \u003cworld\u003e","bar":42,"nested":{"a":1}};</script>`,
+		},
+		{
+			name:   "json data island",
+			format: InjectFormatJSON,
+			want:   `<script type="application/json" id="myNamespace">{"foo":"hello \u003cworld\u003e","bar":42,"nested":{"a":1}}</script>`,
+		},
+		{
+			name:   "meta tags",
+			format: InjectFormatMeta,
+			want:   `<meta name="myNamespace:foo" content="hello &lt;world&gt;"/><meta name="myNamespace:bar" content="42"/><meta name="myNamespace:nested" content="{&#34;a&#34;:1}"/>`,
+		},
+		{
+			name:   "frozen",
+			format: InjectFormatFrozen,
+			want:   `<script type="text/javascript">globalThis.myNamespace = ` + deepFreezeFunc + `({"foo":"hello \u003cworld\u003e","bar":42,"nested":{"a":1}});</script>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			nodes, err := constructEnvNodes(ns, conf, tc.format)
+			if err != nil {
+				t.Fatalf("constructEnvNodes() returned an unexpected error: %v", err)
+			}
+
+			var buf bytes.Buffer
+			for _, n := range nodes {
+				if err := html.Render(&buf, n); err != nil {
+					t.Fatalf("html.Render() returned an unexpected error: %v", err)
+				}
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("constructEnvNodes() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("meta tags require an object", func(t *testing.T) {
+		if _, err := constructEnvNodes(ns, []string{"a"}, InjectFormatMeta); !errors.Is(err, ErrConfigNotAnObject) {
+			t.Errorf("constructEnvNodes() error = %v, want %v", err, ErrConfigNotAnObject)
+		}
+	})
+}
+
+func TestInjectWebEnvWithFormat(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+
+	result, err := InjectWebEnv(fsys, map[string]string{"foo": "bar"}, "myNamespace", WithInjectFormat(InjectFormatMeta))
+	if err != nil {
+		t.Fatalf("InjectWebEnv() returned an unexpected error: %v", err)
+	}
+
+	want := `<html><head><meta name="myNamespace:foo" content="bar"/></head><body></body></html>`
+	got, err := fs.ReadFile(result, "index.html")
+	if err != nil {
+		t.Fatalf("fs.ReadFile() returned an unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("InjectWebEnv() = %s, want %s", got, want)
+	}
+}
+
+func TestStaticFilesHandlerWithInjectFormat(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+
+	handler, err := NewStaticFilesHandler(fsys, WithInjectWebEnv(map[string]string{"foo": "bar"}, "", WithInjectFormat(InjectFormatJSON)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	want := `<script type="application/json" id="APP_ENV">{"foo":"bar"}</script>`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Expected body to contain %q, but got %q", want, w.Body.String())
+	}
+}
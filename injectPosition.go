@@ -0,0 +1,233 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultInjectMarker is the comment marker replaced by InjectAtMarker if none is provided (i.e. <!-- spaserve:env -->)
+const DefaultInjectMarker = "spaserve:env"
+
+type injectPositionKind int
+
+const (
+	injectHeadStart injectPositionKind = iota
+	injectHeadEnd
+	injectBefore
+	injectAfter
+	injectBodyEnd
+	injectMarker
+)
+
+// InjectPosition is where the web environment is injected into html documents. Defaults to InjectAtHeadStart.
+type InjectPosition struct {
+	kind     injectPositionKind
+	selector *selector
+	marker   string
+	err      error
+}
+
+// InjectAtHeadStart injects the web environment as the first children of <head>
+func InjectAtHeadStart() InjectPosition {
+	return InjectPosition{kind: injectHeadStart}
+}
+
+// InjectAtHeadEnd injects the web environment as the last children of <head>
+func InjectAtHeadEnd() InjectPosition {
+	return InjectPosition{kind: injectHeadEnd}
+}
+
+// InjectBefore injects the web environment before the first element matching the selector.
+// The selector is a tag, an attribute or both (e.g. script, [data-env], script[type=importmap]).
+func InjectBefore(sel string) InjectPosition {
+	s, err := parseSelector(sel)
+	return InjectPosition{kind: injectBefore, selector: s, err: err}
+}
+
+// InjectAfter injects the web environment after the first element matching the selector, see InjectBefore.
+func InjectAfter(sel string) InjectPosition {
+	s, err := parseSelector(sel)
+	return InjectPosition{kind: injectAfter, selector: s, err: err}
+}
+
+// InjectAtBodyEnd injects the web environment as the last children of <body>
+func InjectAtBodyEnd() InjectPosition {
+	return InjectPosition{kind: injectBodyEnd}
+}
+
+// InjectAtMarker replaces the first comment with the given marker (e.g. <!-- spaserve:env -->) with the web environment.
+// Defaults to DefaultInjectMarker.
+func InjectAtMarker(marker string) InjectPosition {
+	if marker = strings.TrimSpace(marker); marker == "" {
+		marker = DefaultInjectMarker
+	}
+	return InjectPosition{kind: injectMarker, marker: marker}
+}
+
+// String returns a description of the position used in errors
+func (p InjectPosition) String() string {
+	switch p.kind {
+	case injectHeadEnd:
+		return "head end"
+	case injectBefore:
+		return "before " + p.selector.String()
+	case injectAfter:
+		return "after " + p.selector.String()
+	case injectBodyEnd:
+		return "body end"
+	case injectMarker:
+		return "marker <!-- " + p.marker + " -->"
+	default:
+		return "head start"
+	}
+}
+
+// selector matches elements by tag and attribute
+type selector struct {
+	tag      string
+	attr     string
+	val      string
+	matchVal bool
+}
+
+var selectorRegex = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9-]*)?(?:\[([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:=(?:"([^"]*)"|'([^']*)'|([^\]"'\s]*)))?\])?$`)
+
+// parseSelector parses a selector of the form tag, [attr], [attr=value] or tag[attr=value]
+func parseSelector(sel string) (*selector, error) {
+	sel = strings.TrimSpace(sel)
+	m := selectorRegex.FindStringSubmatchIndex(sel)
+	if sel == "" || m == nil {
+		return nil, errors.Join(ErrCouldNotParseSelector, errors.New(sel))
+	}
+
+	group := func(i int) (string, bool) {
+		if m[2*i] < 0 {
+			return "", false
+		}
+		return sel[m[2*i]:m[2*i+1]], true
+	}
+
+	s := &selector{}
+	s.tag, _ = group(1)
+	s.tag = strings.ToLower(s.tag)
+	s.attr, _ = group(2)
+	s.attr = strings.ToLower(s.attr)
+	for i := 3; i <= 5; i++ {
+		if val, ok := group(i); ok {
+			s.val, s.matchVal = val, true
+		}
+	}
+	return s, nil
+}
+
+// String returns the selector in its canonical form
+func (s *selector) String() string {
+	if s == nil {
+		return ""
+	}
+	str := s.tag
+	if s.attr != "" {
+		str += "[" + s.attr
+		if s.matchVal {
+			str += `="` + s.val + `"`
+		}
+		str += "]"
+	}
+	return str
+}
+
+// matches returns true if the node is an element matching the selector
+func (s *selector) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode || (s.tag != "" && n.Data != s.tag) {
+		return false
+	}
+	if s.attr == "" {
+		return true
+	}
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == s.attr {
+			return !s.matchVal || a.Val == s.val
+		}
+	}
+	return false
+}
+
+// injectAt inserts copies of the nodes at the position of the given html document
+func injectAt(nodes []*html.Node, d []byte, pos InjectPosition) ([]byte, error) {
+	if pos.err != nil {
+		return nil, pos.err
+	}
+
+	// parse document
+	doc, err := html.Parse(bytes.NewReader(d))
+	if err != nil {
+		return []byte{}, errors.Join(ErrCouldNotParseIndex, err)
+	}
+
+	// find the parent and the node to insert before, a nil node appends to the parent
+	var parent, before *html.Node
+	switch pos.kind {
+	case injectHeadStart, injectHeadEnd:
+		head := findHead(doc)
+		if head == nil {
+			return []byte{}, ErrCouldNotFindHead
+		}
+		parent = head
+		if pos.kind == injectHeadStart {
+			before = head.FirstChild
+		}
+	case injectBodyEnd:
+		body := findNode(doc, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "body" })
+		if body == nil {
+			return []byte{}, errors.Join(ErrCouldNotFindAnchor, errors.New(pos.String()))
+		}
+		parent = body
+	case injectBefore, injectAfter:
+		el := findNode(doc, pos.selector.matches)
+		if el == nil || el.Parent == nil {
+			return []byte{}, errors.Join(ErrCouldNotFindAnchor, errors.New(pos.String()))
+		}
+		parent, before = el.Parent, el
+		if pos.kind == injectAfter {
+			before = el.NextSibling
+		}
+	case injectMarker:
+		marker := findNode(doc, func(n *html.Node) bool {
+			return n.Type == html.CommentNode && strings.TrimSpace(n.Data) == pos.marker
+		})
+		if marker == nil || marker.Parent == nil {
+			return []byte{}, errors.Join(ErrCouldNotFindAnchor, errors.New(pos.String()))
+		}
+		parent, before = marker.Parent, marker.NextSibling
+		parent.RemoveChild(marker)
+	}
+
+	// insert copies of the nodes as a node can only be inserted once
+	for _, n := range nodes {
+		parent.InsertBefore(cloneNode(n), before)
+	}
+
+	// render doc to bytes
+	var b bytes.Buffer
+	if err := html.Render(&b, doc); err != nil {
+		return []byte{}, errors.Join(ErrCouldNotWriteIndex, err)
+	}
+	return b.Bytes(), nil
+}
+
+// findNode returns the first node of the document in document order matching the predicate
+func findNode(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findNode(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package spaserve
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/psanford/memfs"
+	"golang.org/x/net/html"
+)
+
+func TestInjectAt(t *testing.T) {
+	node := &html.Node{Type: html.ElementNode, Data: "script", FirstChild: &html.Node{Type: html.TextNode, Data: "env"}}
+	doc := `<html><head><meta charset="utf-8"/><script type="importmap">{}</script><title>app</title></head><body><div id="app"></div><!-- spaserve:env --></body></html>`
+
+	tt := []struct {
+		name     string
+		position InjectPosition
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "head start",
+			position: InjectAtHeadStart(),
+			want:     `<html><head><script>env</script><meta charset="utf-8"/><script type="importmap">{}</script><title>app</title></head><body><div id="app"></div><!-- spaserve:env --></body></html>`,
+		},
+		{
+			name:     "head end",
+			position: InjectAtHeadEnd(),
+			want:     `<html><head><meta charset="utf-8"/><script type="importmap">{}</script><title>app</title><script>env</script></head><body><div id="app"></div><!-- spaserve:env --></body></html>`,
+		},
+		{
+			name:     "after selector",
+			position: InjectAfter(`script[type="importmap"]`),
+			want:     `<html><head><meta charset="utf-8"/><script type="importmap">{}</script><script>env</script><title>app</title></head><body><div id="app"></div><!-- spaserve:env --></body></html>`,
+		},
+		{
+			name:     "before selector",
+			position: InjectBefore("title"),
+			want:     `<html><head><meta charset="utf-8"/><script type="importmap">{}</script><script>env</script><title>app</title></head><body><div id="app"></div><!-- spaserve:env --></body></html>`,
+		},
+		{
+			name:     "after attribute selector",
+			position: InjectAfter("[charset]"),
+			want:     `<html><head><meta charset="utf-8"/><script>env</script><script type="importmap">{}</script><title>app</title></head><body><div id="app"></div><!-- spaserve:env --></body></html>`,
+		},
+		{
+			name:     "body end",
+			position: InjectAtBodyEnd(),
+			want:     `<html><head><meta charset="utf-8"/><script type="importmap">{}</script><title>app</title></head><body><div id="app"></div><!-- spaserve:env --><script>env</script></body></html>`,
+		},
+		{
+			name:     "marker",
+			position: InjectAtMarker(""),
+			want:     `<html><head><meta charset="utf-8"/><script type="importmap">{}</script><title>app</title></head><body><div id="app"></div><script>env</script></body></html>`,
+		},
+		{
+			name:     "missing selector anchor",
+			position: InjectBefore("link[rel=modulepreload]"),
+			wantErr:  ErrCouldNotFindAnchor,
+		},
+		{
+			name:     "missing marker anchor",
+			position: InjectAtMarker("other"),
+			wantErr:  ErrCouldNotFindAnchor,
+		},
+		{
+			name:     "invalid selector",
+			position: InjectBefore("div > p"),
+			wantErr:  ErrCouldNotParseSelector,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := injectAt([]*html.Node{node}, []byte(doc), tc.position)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("injectAt() error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("injectAt() returned an unexpected error: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("injectAt() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	tt := []struct {
+		sel  string
+		want string
+		ok   bool
+	}{
+		{sel: "script", want: "script", ok: true},
+		{sel: "SCRIPT", want: "script", ok: true},
+		{sel: "[data-env]", want: "[data-env]", ok: true},
+		{sel: "script[type=importmap]", want: `script[type="importmap"]`, ok: true},
+		{sel: `meta[name='viewport']`, want: `meta[name="viewport"]`, ok: true},
+		{sel: "", ok: false},
+		{sel: "#app", ok: false},
+		{sel: "div p", ok: false},
+	}
+
+	for _, tc := range tt {
+		s, err := parseSelector(tc.sel)
+		if tc.ok != (err == nil) {
+			t.Errorf("parseSelector(%q) error = %v, want ok %v", tc.sel, err, tc.ok)
+			continue
+		}
+		if tc.ok && s.String() != tc.want {
+			t.Errorf("parseSelector(%q) = %q, want %q", tc.sel, s.String(), tc.want)
+		}
+	}
+}
+
+func TestInjectWebEnvWithPosition(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte(`<html><head><meta charset="utf-8"></head><body></body></html>`), 0644)
+
+	result, err := InjectWebEnv(fsys, map[string]string{"foo": "bar"}, "APP_ENV", WithInjectPosition(InjectAfter("meta[charset]")))
+	if err != nil {
+		t.Fatalf("InjectWebEnv() returned an unexpected error: %v", err)
+	}
+
+	got, _ := fs.ReadFile(result, "index.html")
+	want := `<html><head><meta charset="utf-8"/><script type="text/javascript">window.APP_ENV = {"foo":"bar"};</script></head><body></body></html>`
+	if string(got) != want {
+		t.Errorf("InjectWebEnv() = %s, want %s", got, want)
+	}
+
+	if _, err := InjectWebEnv(fsys, map[string]string{"foo": "bar"}, "APP_ENV", WithInjectPosition(InjectAtMarker(""))); !errors.Is(err, ErrCouldNotFindAnchor) {
+		t.Errorf("InjectWebEnv() error = %v, want %v", err, ErrCouldNotFindAnchor)
+	}
+
+	if _, err := InjectWebEnv(fsys, map[string]string{"foo": "bar"}, "APP_ENV", WithInjectPosition(InjectBefore("a b"))); !errors.Is(err, ErrCouldNotParseSelector) {
+		t.Errorf("InjectWebEnv() error = %v, want %v", err, ErrCouldNotParseSelector)
+	}
+}
@@ -1,43 +1,293 @@
 package spaserve
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"io/fs"
+	"log/slog"
 	"path"
-	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/psanford/memfs"
 	"golang.org/x/net/html"
 )
 
-var namespaceRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+type injectWebEnvOpts struct {
+	format        InjectFormat
+	position      InjectPosition
+	preserveBytes bool
+	documents     func(string) bool
+	logger        *slog.Logger
+	schema        string
+}
 
-// InjectWebEnv injects the web environment into the index.html file of the given file system.
-//   - filesys: the file system to inject the web environment into
-//   - conf: the web environment to inject, use json struct tags to drive the marshalling
-//   - ns: the namespace to use for the web environment, must match regex: ^[a-zA-Z_][a-zA-Z0-9_]*$
-func InjectWebEnv(filesys fs.FS, conf any, ns string) (*memfs.FS, error) {
-	if ns == "" {
-		return nil, ErrNoNamespace
+type injectWebEnvFunc func(injectWebEnvOpts) injectWebEnvOpts
+
+var defaultInjectWebEnvOpts = injectWebEnvOpts{
+	format:        InjectFormatScript,
+	position:      InjectAtHeadStart(),
+	preserveBytes: false,
+	documents:     nil,
+	logger:        nil,
+	schema:        "",
+}
+
+// WithInjectFormat sets the format the web environment is injected in. Defaults to InjectFormatScript.
+func WithInjectFormat(format InjectFormat) injectWebEnvFunc {
+	return func(c injectWebEnvOpts) injectWebEnvOpts {
+		c.format = format
+		return c
+	}
+}
+
+// WithInjectPosition sets where the web environment is injected into the documents. Defaults to InjectAtHeadStart.
+func WithInjectPosition(position InjectPosition) injectWebEnvFunc {
+	return func(c injectWebEnvOpts) injectWebEnvOpts {
+		c.position = position
+		return c
+	}
+}
+
+// WithPreserveBytes splices the web environment into the documents instead of parsing and rendering them, so every
+// other byte (quoting, whitespace, comments) stays untouched, e.g. to keep SRI hashes valid. The anchor of the
+// position has to be written explicitly in the documents.
+func WithPreserveBytes() injectWebEnvFunc {
+	return func(c injectWebEnvOpts) injectWebEnvOpts {
+		c.preserveBytes = true
+		return c
+	}
+}
+
+// WithInjectDocuments injects the web environment into every html document matching one of the glob patterns
+// (e.g. "*.html" or "about/**") instead of only the root index.html. A "**" segment matches any number of directories
+// and patterns without a slash are matched against the base name.
+func WithInjectDocuments(patterns ...string) injectWebEnvFunc {
+	return WithInjectDocumentFunc(func(name string) bool {
+		for _, pattern := range patterns {
+			if matchGlob(pattern, name) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithInjectDocumentFunc injects the web environment into every html document for which the predicate returns true
+// instead of only the root index.html. The predicate is called with the slash separated path of the document.
+func WithInjectDocumentFunc(fn func(name string) bool) injectWebEnvFunc {
+	return func(c injectWebEnvOpts) injectWebEnvOpts {
+		c.documents = fn
+		return c
+	}
+}
+
+// selectDocuments returns the html documents of the file system selected by the options,
+// or the given default documents which must exist if no selector is configured
+func (c injectWebEnvOpts) selectDocuments(filesys fs.FS, defaults []string) ([]string, error) {
+	if c.documents == nil {
+		for _, document := range defaults {
+			if !fileExists(filesys, document) {
+				return nil, errors.Join(ErrNoIndexFound, errors.New(document))
+			}
+		}
+		return defaults, nil
+	}
+
+	var documents []string
+	err := fs.WalkDir(filesys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Join(ErrUnexpectedWalkError, err)
+		}
+		if !d.IsDir() && isHTMLDocument(p) && c.documents(p) {
+			documents = append(documents, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	ns = strings.TrimSpace(ns)
-	if !namespaceRegex.Match([]byte(ns)) {
-		return nil, ErrCouldNotParseNamespace
+
+	if len(documents) == 0 {
+		return nil, errors.Join(ErrNoIndexFound, errors.New("no html document matches the selector"))
 	}
+	return documents, nil
+}
 
-	if !indexExists(filesys) {
-		return nil, ErrNoIndexFound
+// isHTMLDocument returns true if the name has an html extension
+func isHTMLDocument(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
+
+// WithInjectLogger sets the logger warning about fields of the web environment which look like secrets
+// (e.g. password, token or secret). Defaults to the logger of the handler.
+func WithInjectLogger(logger *slog.Logger) injectWebEnvFunc {
+	return func(c injectWebEnvOpts) injectWebEnvOpts {
+		c.logger = logger
+		return c
+	}
+}
+
+// WithSchema validates the marshalled web environment against the JSON Schema file of the file system
+// (e.g. "env.schema.json") before it is injected, and fails with a SchemaValidationError listing every violation.
+// Schemas using keywords which are not supported (e.g. if or prefixItems) fail with ErrCouldNotParseSchema, format is
+// an annotation only and not asserted.
+// It is not validated when proxying to a dev server as there is no file system to read the schema from.
+func WithSchema(name string) injectWebEnvFunc {
+	return func(c injectWebEnvOpts) injectWebEnvOpts {
+		c.schema = name
+		return c
 	}
+}
 
-	scriptTag, err := constructScriptTag(ns, conf)
+// inject injects the nodes into the html document at the configured position
+func (c injectWebEnvOpts) inject(nodes []*html.Node, d []byte) ([]byte, error) {
+	if c.preserveBytes {
+		return spliceAt(nodes, d, c.position)
+	}
+	return injectAt(nodes, d, c.position)
+}
+
+// InjectWebEnv injects the web environment into the index.html file of the given file system,
+// or into the html documents selected with WithInjectDocuments or WithInjectDocumentFunc.
+//   - filesys: the file system to inject the web environment into
+//   - conf: the web environment to inject, use json struct tags to drive the marshalling
+//   - ns: the namespace to use for the web environment, a dotted path (e.g. __SHELL__.apps.billing) of segments
+//     matching regex: ^[a-zA-Z_][a-zA-Z0-9_]*$
+//   - fn: optional functions to configure the injection (e.g. WithInjectFormat, WithInjectPosition, WithSchema)
+func InjectWebEnv(filesys fs.FS, conf any, ns string, fn ...injectWebEnvFunc) (*memfs.FS, error) {
+	return injectWebEnv(filesys, conf, ns, []string{"index.html"}, fn...)
+}
+
+// injectWebEnv injects the web environment into each of the given documents unless the options select the documents
+func injectWebEnv(filesys fs.FS, conf any, ns string, documents []string, fn ...injectWebEnvFunc) (*memfs.FS, error) {
+	hook, err := webEnvHook(filesys, []webEnvInjection{{env: conf, ns: ns, fn: fn}}, documents, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return CopyFileSys(filesys, appendToIndex(scriptTag))
+	return CopyFileSys(filesys, hook)
+}
+
+// webEnvInjection is a web environment injected into html documents under its namespace
+type webEnvInjection struct {
+	env any
+	ns  string
+	fn  []injectWebEnvFunc
+}
+
+// preparedWebEnv is a validated web environment with its constructed nodes and selected documents
+type preparedWebEnv struct {
+	env       any
+	opts      injectWebEnvOpts
+	nodes     []*html.Node
+	documents []string
+}
+
+// webEnvHook validates the web environments and returns the hook injecting them into each of their selected documents
+func webEnvHook(filesys fs.FS, injections []webEnvInjection, documents []string, logger *slog.Logger) (OnHookFunc, error) {
+	envs, err := prepareWebEnvs(injections, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range envs {
+		if envs[i].opts.schema != "" {
+			if err := validateWebEnvSchema(filesys, envs[i].opts.schema, envs[i].env); err != nil {
+				return nil, err
+			}
+		}
+		if envs[i].documents, err = envs[i].opts.selectDocuments(filesys, documents); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(p string, d []byte) ([]byte, error) {
+		var selected []preparedWebEnv
+		for _, env := range envs {
+			if slices.Contains(env.documents, p) {
+				selected = append(selected, env)
+			}
+		}
+		if len(selected) == 0 {
+			return d, nil
+		}
+		return injectWebEnvs(selected, d)
+	}, nil
+}
+
+// prepareWebEnvs validates the namespaces, options and fields of the web environments and constructs their nodes.
+// Fields which look like secrets are logged with the logger of the injection options or the given logger.
+func prepareWebEnvs(injections []webEnvInjection, logger *slog.Logger) ([]preparedWebEnv, error) {
+	envs := make([]preparedWebEnv, 0, len(injections))
+	seen := map[string]bool{}
+	for _, injection := range injections {
+		// process options
+		opts := defaultInjectWebEnvOpts
+		for _, f := range injection.fn {
+			opts = f(opts)
+		}
+
+		ns, err := validateNamespace(injection.ns)
+		if err != nil {
+			return nil, err
+		}
+		for other := range seen {
+			if namespacesCollide(ns, other) {
+				return nil, errors.Join(ErrDuplicateNamespace, errors.New(ns))
+			}
+		}
+		seen[ns] = true
+
+		if opts.position.err != nil {
+			return nil, opts.position.err
+		}
+
+		// refuse to make secrets public
+		if opts.logger == nil {
+			opts.logger = logger
+		}
+		if err := validateSecrets(newLogger(opts.logger), ns, injection.env); err != nil {
+			return nil, err
+		}
+
+		nodes, err := constructEnvNodes(ns, injection.env, opts.format)
+		if err != nil {
+			return nil, err
+		}
+
+		envs = append(envs, preparedWebEnv{env: injection.env, opts: opts, nodes: nodes})
+	}
+	return envs, nil
+}
+
+// injectWebEnvs injects the web environments into the html document in order. Web environments sharing a position
+// are injected together, so they appear in the document in the same order as they are configured.
+func injectWebEnvs(envs []preparedWebEnv, d []byte) ([]byte, error) {
+	var (
+		keys   []string
+		groups = map[string]*preparedWebEnv{}
+	)
+	for _, env := range envs {
+		key := env.opts.position.String() + "\x00" + strconv.FormatBool(env.opts.preserveBytes)
+		group, ok := groups[key]
+		if !ok {
+			group = &preparedWebEnv{opts: env.opts}
+			groups[key] = group
+			keys = append(keys, key)
+		}
+		group.nodes = append(group.nodes, env.nodes...)
+	}
+
+	for _, key := range keys {
+		var err error
+		if d, err = groups[key].opts.inject(groups[key].nodes, d); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
 }
 
 // indexExists returns true if the index.html file exists in the given file system
@@ -60,11 +310,16 @@ func constructScriptTag(ns string, conf any) (*html.Node, error) {
 		Attr: []html.Attribute{{Key: "type", Val: "text/javascript"}},
 		FirstChild: &html.Node{
 			Type: html.TextNode,
-			Data: "window." + ns + " = " + string(b) + ";",
+			Data: windowAssignment(ns, b),
 		},
 	}, nil
 }
 
+// windowAssignment returns the javascript statement assigning the marshalled config to the namespace of window
+func windowAssignment(ns string, b []byte) string {
+	return namespaceAssignment("window", ns, string(b))
+}
+
 // appendToIndex returns a function that appends a script tag to the head of the index.html file
 func appendToIndex(t *html.Node) func(string, []byte) ([]byte, error) {
 	return func(p string, d []byte) ([]byte, error) {
@@ -73,28 +328,28 @@ func appendToIndex(t *html.Node) func(string, []byte) ([]byte, error) {
 			return d, nil
 		}
 
-		// parse index.html
-		doc, err := html.Parse(bytes.NewReader(d))
-		if err != nil {
-			return []byte{}, errors.Join(ErrCouldNotParseIndex, err)
-		}
-
-		// find head tag
-		headTag := findHead(doc)
-		if headTag == nil {
-			return []byte{}, ErrCouldNotFindHead
-		}
+		return appendToHead([]*html.Node{t}, d)
+	}
+}
 
-		// insert script before first child of head
-		headTag.InsertBefore(t, headTag.FirstChild)
+// appendToHead inserts the nodes as the first children of the head of the given html document
+func appendToHead(nodes []*html.Node, d []byte) ([]byte, error) {
+	return injectAt(nodes, d, InjectAtHeadStart())
+}
 
-		// render doc to bytes
-		var b bytes.Buffer
-		if err := html.Render(&b, doc); err != nil {
-			return []byte{}, errors.Join(ErrCouldNotWriteIndex, err)
-		}
-		return b.Bytes(), nil
+// cloneNode returns a deep copy of the node without parent and siblings
+func cloneNode(n *html.Node) *html.Node {
+	c := &html.Node{
+		Type:      n.Type,
+		DataAtom:  n.DataAtom,
+		Data:      n.Data,
+		Namespace: n.Namespace,
+		Attr:      slices.Clone(n.Attr),
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.AppendChild(cloneNode(child))
 	}
+	return c
 }
 
 // findHead recursively searches for the head tag in the html document
@@ -3,6 +3,8 @@ package spaserve
 import (
 	"bytes"
 	"errors"
+	"io/fs"
+	"slices"
 	"testing"
 
 	"github.com/psanford/memfs"
@@ -260,3 +262,86 @@ func TestConstructScriptTag_MarshalError(t *testing.T) {
 		t.Errorf("constructScriptTag() error = %v, want %v", err, wantErr)
 	}
 }
+
+func TestInjectWebEnvDocuments(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.MkdirAll("about", 0755)
+	for _, name := range []string{"index.html", "login.html", "about/index.html", "about/app.js"} {
+		_ = fsys.WriteFile(name, []byte("<html><head></head><body></body></html>"), 0644)
+	}
+
+	tt := []struct {
+		name     string
+		fn       []injectWebEnvFunc
+		injected []string
+		wantErr  error
+	}{
+		{
+			name:     "defaults to the root index",
+			injected: []string{"index.html"},
+		},
+		{
+			name:     "all html documents",
+			fn:       []injectWebEnvFunc{WithInjectDocuments("*.html")},
+			injected: []string{"index.html", "login.html", "about/index.html"},
+		},
+		{
+			name:     "documents of a directory",
+			fn:       []injectWebEnvFunc{WithInjectDocuments("about/**")},
+			injected: []string{"about/index.html"},
+		},
+		{
+			name:     "predicate",
+			fn:       []injectWebEnvFunc{WithInjectDocumentFunc(func(name string) bool { return name != "index.html" })},
+			injected: []string{"login.html", "about/index.html"},
+		},
+		{
+			name:    "no document matches",
+			fn:      []injectWebEnvFunc{WithInjectDocuments("admin/**")},
+			wantErr: ErrNoIndexFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := InjectWebEnv(fsys, map[string]string{"foo": "bar"}, "APP_ENV", tc.fn...)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("InjectWebEnv() error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InjectWebEnv() returned an unexpected error: %v", err)
+			}
+
+			for _, name := range []string{"index.html", "login.html", "about/index.html", "about/app.js"} {
+				data, _ := fs.ReadFile(result, name)
+				want := slices.Contains(tc.injected, name)
+				if got := bytes.Contains(data, []byte("window.APP_ENV")); got != want {
+					t.Errorf("%s injected = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInjectWebEnvDottedNamespace(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+
+	result, err := InjectWebEnv(fsys, map[string]string{"api": "/billing"}, "__SHELL__.apps.billing")
+	if err != nil {
+		t.Fatalf("InjectWebEnv() returned an unexpected error: %v", err)
+	}
+
+	got, _ := fs.ReadFile(result, "index.html")
+	want := `<html><head><script type="text/javascript">window.__SHELL__ = window.__SHELL__ || {}; window.__SHELL__.apps = window.__SHELL__.apps || {}; window.__SHELL__.apps.billing = {"api":"/billing"};</script></head><body></body></html>`
+	if string(got) != want {
+		t.Errorf("InjectWebEnv() = %s, want %s", got, want)
+	}
+
+	if _, err := InjectWebEnv(fsys, map[string]string{}, "window.APP_ENV"); !errors.Is(err, ErrReservedNamespace) {
+		t.Errorf("InjectWebEnv() error = %v, want %v", err, ErrReservedNamespace)
+	}
+}
@@ -0,0 +1,229 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// regexKeywords are the keywords after which a slash starts a regular expression literal rather than a division
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true, "delete": true,
+	"void": true, "throw": true, "case": true, "do": true, "else": true, "yield": true, "await": true,
+}
+
+// jsLiterals are the string and template literals of javascript code
+type jsLiterals struct {
+	// strings holds the end offsets of the single and double quoted string literals by their start offset
+	strings map[int]int
+	// templates holds the byte ranges of the text of template literals, substitutions are not included
+	templates [][2]int
+}
+
+// isWholeString returns true if a quoted string literal spans exactly from start to end
+func (l jsLiterals) isWholeString(start int, end int) bool {
+	e, ok := l.strings[start]
+	return ok && e == end
+}
+
+// inString returns true if the offset is inside a string literal or the text of a template literal
+func (l jsLiterals) inString(offset int) bool {
+	for start, end := range l.strings {
+		if offset > start && offset < end {
+			return true
+		}
+	}
+	for _, r := range l.templates {
+		if offset >= r[0] && offset < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds the literals shifted by the offset, e.g. to collect the literals of several scripts of a document
+func (l jsLiterals) add(other jsLiterals, offset int) jsLiterals {
+	for start, end := range other.strings {
+		l.strings[offset+start] = offset + end
+	}
+	for _, r := range other.templates {
+		l.templates = append(l.templates, [2]int{offset + r[0], offset + r[1]})
+	}
+	return l
+}
+
+// lexJSLiterals returns the string and template literals of the javascript code. Comments and regular expression
+// literals are skipped, so quotes inside them do not start a string literal. It is a lexer for finding literals,
+// not a validator, and never fails.
+func lexJSLiterals(code []byte) jsLiterals {
+	literals := jsLiterals{strings: map[int]int{}}
+
+	var (
+		// substitutions holds the brace depth at which each open template substitution ${ started
+		substitutions []int
+		depth         int
+		// regexOK is true if a slash starts a regular expression literal rather than a division
+		regexOK = true
+	)
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := scanQuoted(code, i)
+			literals.strings[i] = end
+			i, regexOK = end, false
+		case c == '`':
+			i, regexOK = scanTemplate(code, i+1, &literals, &substitutions, &depth)
+		case c == '{':
+			depth++
+			i, regexOK = i+1, true
+		case c == '}':
+			depth--
+			if n := len(substitutions); n > 0 && substitutions[n-1] == depth {
+				// the substitution ends and the template continues
+				substitutions = substitutions[:n-1]
+				i, regexOK = scanTemplate(code, i+1, &literals, &substitutions, &depth)
+				continue
+			}
+			i, regexOK = i+1, true
+		case c == '/' && i+1 < len(code) && code[i+1] == '/':
+			if end := bytes.IndexByte(code[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(code)
+			}
+		case c == '/' && i+1 < len(code) && code[i+1] == '*':
+			if end := bytes.Index(code[i+2:], []byte("*/")); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(code)
+			}
+		case c == '/' && regexOK:
+			i, regexOK = scanRegex(code, i+1), false
+		case isJSIdentByte(c):
+			end := i + 1
+			for end < len(code) && isJSIdentByte(code[end]) {
+				end++
+			}
+			i, regexOK = end, regexKeywords[string(code[i:end])]
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == ')' || c == ']':
+			i, regexOK = i+1, false
+		default:
+			i, regexOK = i+1, true
+		}
+	}
+	return literals
+}
+
+// scanQuoted returns the offset after the string literal starting with the quote at i
+func scanQuoted(code []byte, i int) int {
+	quote := code[i]
+	for j := i + 1; j < len(code); j++ {
+		switch code[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			// unterminated literal
+			return j
+		}
+	}
+	return len(code)
+}
+
+// scanTemplate scans the text of the template literal from i until its end or the start of a substitution, which is
+// pushed onto the substitutions, and adds the text to the literals. It returns the offset to continue lexing at and
+// whether a slash there starts a regular expression.
+func scanTemplate(code []byte, i int, literals *jsLiterals, substitutions *[]int, depth *int) (int, bool) {
+	start := i
+	for ; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '`':
+			literals.templates = append(literals.templates, [2]int{start, i})
+			return i + 1, false
+		case '$':
+			if i+1 < len(code) && code[i+1] == '{' {
+				literals.templates = append(literals.templates, [2]int{start, i})
+				*substitutions = append(*substitutions, *depth)
+				*depth++
+				return i + 2, true
+			}
+		}
+	}
+	literals.templates = append(literals.templates, [2]int{start, len(code)})
+	return len(code), false
+}
+
+// scanRegex returns the offset after the regular expression literal whose body starts at i, including its flags
+func scanRegex(code []byte, i int) int {
+	class := false
+	for ; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '\n':
+			return i
+		case '/':
+			if class {
+				continue
+			}
+			for i++; i < len(code) && isJSIdentByte(code[i]); i++ {
+			}
+			return i
+		}
+	}
+	return len(code)
+}
+
+// isJSIdentByte returns true if c can be part of an identifier, keyword or number
+func isJSIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// scriptRanges returns the byte ranges of the contents of the script elements of the html document
+func scriptRanges(d []byte) ([][2]int, error) {
+	z := html.NewTokenizer(bytes.NewReader(d))
+
+	var (
+		ranges   [][2]int
+		offset   int
+		inScript bool
+	)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if !errors.Is(z.Err(), io.EOF) {
+				return nil, errors.Join(ErrCouldNotParseIndex, z.Err())
+			}
+			return ranges, nil
+		}
+
+		start := offset
+		offset += len(z.Raw())
+
+		switch tt {
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			inScript = string(name) == "script"
+		case html.TextToken:
+			if inScript {
+				ranges = append(ranges, [2]int{start, offset})
+			}
+			inScript = false
+		default:
+			inScript = false
+		}
+	}
+}
@@ -0,0 +1,621 @@
+package spaserve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaViolation is a single mismatch between the web environment and its JSON Schema
+type SchemaViolation struct {
+	// Path is the JSON pointer of the invalid value (e.g. /api/retries), empty for the root
+	Path string
+	// Message describes the violated keyword
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	p := v.Path
+	if p == "" {
+		p = "/"
+	}
+	return p + ": " + v.Message
+}
+
+// SchemaValidationError is returned when the web environment does not match its JSON Schema and lists every violation
+type SchemaValidationError struct {
+	// Schema is the name of the schema file
+	Schema     string
+	Violations []SchemaViolation
+}
+
+func (e *SchemaValidationError) Error() string {
+	violations := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		violations[i] = v.String()
+	}
+	return ErrSchemaViolation.Error() + " " + e.Schema + ": " + strings.Join(violations, "; ")
+}
+
+// Unwrap allows errors.Is(err, ErrSchemaViolation)
+func (e *SchemaValidationError) Unwrap() error {
+	return ErrSchemaViolation
+}
+
+// validateWebEnvSchema validates the web environment against the JSON Schema file of the file system
+func validateWebEnvSchema(filesys fs.FS, name string, conf any) error {
+	schema, err := loadJSONSchema(filesys, name)
+	if err != nil {
+		return err
+	}
+
+	violations, err := schema.validate(conf)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &SchemaValidationError{Schema: name, Violations: violations}
+	}
+	return nil
+}
+
+// jsonSchema validates JSON values against a JSON Schema. It supports the keywords commonly emitted by schema
+// generators: type, enum, const, properties, required, additionalProperties, patternProperties, propertyNames,
+// minProperties, maxProperties, dependentRequired, items, contains, minItems, maxItems, uniqueItems, minLength,
+// maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, allOf, anyOf, oneOf, not and
+// local $ref (e.g. #/definitions/Config). Annotations (e.g. title, description or format) are ignored and any other
+// keyword fails parsing, so no part of the schema is silently left unchecked.
+type jsonSchema struct {
+	root     any
+	patterns map[string]*regexp.Regexp
+}
+
+// loadJSONSchema reads and parses the JSON Schema from the file system
+func loadJSONSchema(filesys fs.FS, name string) (*jsonSchema, error) {
+	b, err := fs.ReadFile(filesys, name)
+	if err != nil {
+		return nil, errors.Join(ErrCouldNotReadFile, err)
+	}
+	return parseJSONSchema(b)
+}
+
+// parseJSONSchema parses the JSON Schema and compiles its patterns so errors surface before validation
+func parseJSONSchema(b []byte) (*jsonSchema, error) {
+	var root any
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, errors.Join(ErrCouldNotParseSchema, err)
+	}
+
+	s := &jsonSchema{root: root, patterns: map[string]*regexp.Regexp{}}
+	if err := s.checkSchema(root, ""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// schemaKeywords are the keywords validated by jsonSchema
+var schemaKeywords = map[string]bool{
+	"type": true, "enum": true, "const": true, "properties": true, "required": true, "additionalProperties": true,
+	"patternProperties": true, "propertyNames": true, "minProperties": true, "maxProperties": true,
+	"dependentRequired": true, "items": true, "contains": true, "minItems": true, "maxItems": true, "uniqueItems": true,
+	"minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true, "exclusiveMinimum": true,
+	"exclusiveMaximum": true, "multipleOf": true, "allOf": true, "anyOf": true, "oneOf": true, "not": true, "$ref": true,
+}
+
+// schemaAnnotations are the keywords which do not affect validation. Like most validators format is an annotation
+// only, it documents the value (e.g. uri or date-time) but is not asserted.
+var schemaAnnotations = map[string]bool{
+	"$schema": true, "$id": true, "id": true, "$comment": true, "title": true, "description": true, "default": true,
+	"examples": true, "readOnly": true, "writeOnly": true, "deprecated": true, "definitions": true, "$defs": true,
+	"format": true,
+}
+
+// checkSchema fails on keywords which are not supported and compiles the regular expressions of the schema and all
+// of its sub schemas
+//   - path: the JSON pointer of the schema for error messages
+func (s *jsonSchema) checkSchema(schema any, path string) error {
+	node, ok := schema.(map[string]any)
+	if !ok {
+		// boolean schemas have no keywords, anything else fails during validation
+		return nil
+	}
+
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !schemaKeywords[key] && !schemaAnnotations[key] {
+			return errors.Join(ErrCouldNotParseSchema, fmt.Errorf("unsupported keyword %q at %q", key, path+"/"+escapeJSONPointer(key)))
+		}
+		if _, isBool := node[key].(bool); isBool && (key == "exclusiveMinimum" || key == "exclusiveMaximum") {
+			return errors.Join(ErrCouldNotParseSchema, fmt.Errorf("unsupported draft-04 boolean %q at %q", key, path+"/"+key))
+		}
+	}
+
+	if pattern, ok := node["pattern"].(string); ok {
+		if err := s.compile(pattern); err != nil {
+			return err
+		}
+	}
+
+	// check the sub schemas of keywords holding a schema, a list or a map of schemas
+	for _, key := range []string{"additionalProperties", "propertyNames", "items", "contains", "not"} {
+		if err := s.checkSubSchemas(node[key], path+"/"+key); err != nil {
+			return err
+		}
+	}
+	for _, key := range []string{"properties", "patternProperties", "definitions", "$defs"} {
+		subs, _ := node[key].(map[string]any)
+		for name, sub := range subs {
+			if key == "patternProperties" {
+				if err := s.compile(name); err != nil {
+					return err
+				}
+			}
+			if err := s.checkSchema(sub, path+"/"+key+"/"+escapeJSONPointer(name)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+		if err := s.checkSubSchemas(node[key], path+"/"+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSubSchemas checks a sub schema or each sub schema of a list
+func (s *jsonSchema) checkSubSchemas(schema any, path string) error {
+	subs, ok := schema.([]any)
+	if !ok {
+		return s.checkSchema(schema, path)
+	}
+	for i, sub := range subs {
+		if err := s.checkSchema(sub, path+"/"+strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compile compiles and caches the regular expression
+func (s *jsonSchema) compile(pattern string) error {
+	if _, ok := s.patterns[pattern]; ok {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return errors.Join(ErrCouldNotParseSchema, err)
+	}
+	s.patterns[pattern] = re
+	return nil
+}
+
+// validate returns the violations of the marshalled config
+func (s *jsonSchema) validate(conf any) ([]SchemaViolation, error) {
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+	}
+
+	var value any
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+	}
+
+	var violations []SchemaViolation
+	if err := s.validateValue(s.root, value, "", &violations, 0); err != nil {
+		return nil, err
+	}
+	return violations, nil
+}
+
+// maxSchemaDepth guards against cyclic $ref
+const maxSchemaDepth = 64
+
+// validateValue appends the violations of the value against the schema
+func (s *jsonSchema) validateValue(schema any, value any, path string, violations *[]SchemaViolation, depth int) error {
+	if depth > maxSchemaDepth {
+		return errors.Join(ErrCouldNotParseSchema, errors.New("schema nesting too deep, is $ref cyclic?"))
+	}
+
+	var node map[string]any
+	switch sch := schema.(type) {
+	case bool:
+		if !sch {
+			*violations = append(*violations, SchemaViolation{Path: path, Message: "no value is allowed"})
+		}
+		return nil
+	case map[string]any:
+		node = sch
+	default:
+		return errors.Join(ErrCouldNotParseSchema, fmt.Errorf("schema at %q must be an object or boolean", path))
+	}
+
+	violate := func(format string, args ...any) {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if ref, ok := node["$ref"].(string); ok {
+		target, err := s.resolveRef(ref)
+		if err != nil {
+			return err
+		}
+		if err := s.validateValue(target, value, path, violations, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if t, ok := node["type"]; ok && !matchesType(t, value) {
+		violate("expected type %s but got %s", formatType(t), jsonType(value))
+		// the remaining keywords assume the type matches
+		return nil
+	}
+
+	if enum, ok := node["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violate("value must be one of %s", mustMarshal(enum))
+		}
+	}
+
+	if c, ok := node["const"]; ok && !reflect.DeepEqual(c, value) {
+		violate("value must be %s", mustMarshal(c))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		if err := s.validateObject(node, v, path, violations, depth); err != nil {
+			return err
+		}
+	case []any:
+		if err := s.validateArray(node, v, path, violations, depth); err != nil {
+			return err
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if limit, ok := node["minLength"].(float64); ok && length < limit {
+			violate("length must be at least %v", limit)
+		}
+		if limit, ok := node["maxLength"].(float64); ok && length > limit {
+			violate("length must be at most %v", limit)
+		}
+		if pattern, ok := node["pattern"].(string); ok && !s.patterns[pattern].MatchString(v) {
+			violate("value must match pattern %s", pattern)
+		}
+	case float64:
+		if limit, ok := node["minimum"].(float64); ok && v < limit {
+			violate("value must be at least %v", limit)
+		}
+		if limit, ok := node["maximum"].(float64); ok && v > limit {
+			violate("value must be at most %v", limit)
+		}
+		if limit, ok := node["exclusiveMinimum"].(float64); ok && v <= limit {
+			violate("value must be greater than %v", limit)
+		}
+		if limit, ok := node["exclusiveMaximum"].(float64); ok && v >= limit {
+			violate("value must be less than %v", limit)
+		}
+		if m, ok := node["multipleOf"].(float64); ok && m > 0 {
+			if q := v / m; math.Abs(q-math.Round(q)) > 1e-9 {
+				violate("value must be a multiple of %v", m)
+			}
+		}
+	}
+
+	return s.validateCombinators(node, value, path, violations, depth)
+}
+
+// validateObject validates the object keywords
+func (s *jsonSchema) validateObject(node map[string]any, v map[string]any, path string, violations *[]SchemaViolation, depth int) error {
+	if required, ok := node["required"].([]any); ok {
+		for _, r := range required {
+			if key, ok := r.(string); ok {
+				if _, ok := v[key]; !ok {
+					*violations = append(*violations, SchemaViolation{Path: path, Message: "missing required property " + strconv.Quote(key)})
+				}
+			}
+		}
+	}
+
+	count := float64(len(v))
+	if limit, ok := node["minProperties"].(float64); ok && count < limit {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("must have at least %v properties", limit)})
+	}
+	if limit, ok := node["maxProperties"].(float64); ok && count > limit {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("must have at most %v properties", limit)})
+	}
+
+	props, _ := node["properties"].(map[string]any)
+	patternProps, _ := node["patternProperties"].(map[string]any)
+	additional, hasAdditional := node["additionalProperties"]
+
+	// validate in key order so violations are deterministic
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	dependentRequired, _ := node["dependentRequired"].(map[string]any)
+	propertyNames, hasPropertyNames := node["propertyNames"]
+
+	for _, key := range keys {
+		childPath := path + "/" + escapeJSONPointer(key)
+		if dependents, ok := dependentRequired[key].([]any); ok {
+			for _, d := range dependents {
+				if dependent, ok := d.(string); ok {
+					if _, ok := v[dependent]; !ok {
+						*violations = append(*violations, SchemaViolation{Path: path, Message: "missing property " + strconv.Quote(dependent) + " required by " + strconv.Quote(key)})
+					}
+				}
+			}
+		}
+		if hasPropertyNames {
+			var nameViolations []SchemaViolation
+			if err := s.validateValue(propertyNames, key, childPath, &nameViolations, depth+1); err != nil {
+				return err
+			}
+			if len(nameViolations) > 0 {
+				*violations = append(*violations, SchemaViolation{Path: path, Message: "property name " + strconv.Quote(key) + " does not match the schema of propertyNames"})
+			}
+		}
+
+		matched := false
+		if sub, ok := props[key]; ok {
+			matched = true
+			if err := s.validateValue(sub, v[key], childPath, violations, depth+1); err != nil {
+				return err
+			}
+		}
+		for pattern, sub := range patternProps {
+			if s.patterns[pattern].MatchString(key) {
+				matched = true
+				if err := s.validateValue(sub, v[key], childPath, violations, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				*violations = append(*violations, SchemaViolation{Path: path, Message: "additional property " + strconv.Quote(key) + " is not allowed"})
+				continue
+			}
+			if err := s.validateValue(additional, v[key], childPath, violations, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateArray validates the array keywords
+func (s *jsonSchema) validateArray(node map[string]any, v []any, path string, violations *[]SchemaViolation, depth int) error {
+	length := float64(len(v))
+	if limit, ok := node["minItems"].(float64); ok && length < limit {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("must have at least %v items", limit)})
+	}
+	if limit, ok := node["maxItems"].(float64); ok && length > limit {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("must have at most %v items", limit)})
+	}
+	if unique, ok := node["uniqueItems"].(bool); ok && unique {
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if reflect.DeepEqual(v[i], v[j]) {
+					*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("items %d and %d must be unique", i, j)})
+				}
+			}
+		}
+	}
+
+	if contains, ok := node["contains"]; ok {
+		found := false
+		for _, item := range v {
+			var itemViolations []SchemaViolation
+			if err := s.validateValue(contains, item, path, &itemViolations, depth+1); err != nil {
+				return err
+			}
+			if len(itemViolations) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*violations = append(*violations, SchemaViolation{Path: path, Message: "must contain an item matching the schema of contains"})
+		}
+	}
+
+	switch items := node["items"].(type) {
+	case map[string]any, bool:
+		for i, item := range v {
+			if err := s.validateValue(items, item, path+"/"+strconv.Itoa(i), violations, depth+1); err != nil {
+				return err
+			}
+		}
+	case []any:
+		// tuple validation of draft-07 and earlier
+		for i, item := range v {
+			if i >= len(items) {
+				break
+			}
+			if err := s.validateValue(items[i], item, path+"/"+strconv.Itoa(i), violations, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateCombinators validates allOf, anyOf, oneOf and not
+func (s *jsonSchema) validateCombinators(node map[string]any, value any, path string, violations *[]SchemaViolation, depth int) error {
+	// count returns the number of sub schemas the value is valid against
+	count := func(subs []any) (int, error) {
+		n := 0
+		for _, sub := range subs {
+			var subViolations []SchemaViolation
+			if err := s.validateValue(sub, value, path, &subViolations, depth+1); err != nil {
+				return 0, err
+			}
+			if len(subViolations) == 0 {
+				n++
+			}
+		}
+		return n, nil
+	}
+
+	if allOf, ok := node["allOf"].([]any); ok {
+		for _, sub := range allOf {
+			if err := s.validateValue(sub, value, path, violations, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	if anyOf, ok := node["anyOf"].([]any); ok {
+		n, err := count(anyOf)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			*violations = append(*violations, SchemaViolation{Path: path, Message: "value must match at least one schema of anyOf"})
+		}
+	}
+	if oneOf, ok := node["oneOf"].([]any); ok {
+		n, err := count(oneOf)
+		if err != nil {
+			return err
+		}
+		if n != 1 {
+			*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf("value must match exactly one schema of oneOf but matches %d", n)})
+		}
+	}
+	if not, ok := node["not"]; ok {
+		n, err := count([]any{not})
+		if err != nil {
+			return err
+		}
+		if n == 1 {
+			*violations = append(*violations, SchemaViolation{Path: path, Message: "value must not match the schema of not"})
+		}
+	}
+	return nil
+}
+
+// resolveRef resolves a local reference (e.g. #/definitions/Config or #/$defs/Config) in the root schema
+func (s *jsonSchema) resolveRef(ref string) (any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, errors.Join(ErrCouldNotParseSchema, errors.New("only local $ref are supported: "+ref))
+	}
+	pointer, err := url.PathUnescape(pointer)
+	if err != nil {
+		return nil, errors.Join(ErrCouldNotParseSchema, err)
+	}
+
+	node := s.root
+	if pointer == "" {
+		return node, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch n := node.(type) {
+		case map[string]any:
+			if node, ok = n[token]; !ok {
+				return nil, errors.Join(ErrCouldNotParseSchema, errors.New("could not resolve $ref "+ref))
+			}
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) {
+				return nil, errors.Join(ErrCouldNotParseSchema, errors.New("could not resolve $ref "+ref))
+			}
+			node = n[i]
+		default:
+			return nil, errors.Join(ErrCouldNotParseSchema, errors.New("could not resolve $ref "+ref))
+		}
+	}
+	return node, nil
+}
+
+// matchesType returns true if the value matches the type keyword, which is a type name or a list of type names
+func matchesType(t any, value any) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesTypeName(tt, value)
+	case []any:
+		for _, name := range tt {
+			if s, ok := name.(string); ok && matchesTypeName(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchesTypeName returns true if the value is of the JSON Schema type
+func matchesTypeName(name string, value any) bool {
+	actual := jsonType(value)
+	if name == "number" && actual == "integer" {
+		return true
+	}
+	return name == actual
+}
+
+// jsonType returns the JSON Schema type of the unmarshalled value
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+// formatType returns the type keyword for messages
+func formatType(t any) string {
+	if s, ok := t.(string); ok {
+		return s
+	}
+	return mustMarshal(t)
+}
+
+// mustMarshal returns the JSON of a value which was unmarshalled before
+func mustMarshal(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// escapeJSONPointer escapes a key as JSON pointer token
+func escapeJSONPointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,226 @@
+package spaserve
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+const testSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"$ref": "#/definitions/Env",
+	"definitions": {
+		"Env": {
+			"type": "object",
+			"properties": {
+				"apiUrl": {"type": "string", "pattern": "^https://"},
+				"retries": {"type": "integer", "minimum": 0, "maximum": 5},
+				"mode": {"enum": ["light", "dark"]},
+				"tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "uniqueItems": true},
+				"flags": {"type": "object", "additionalProperties": {"type": "boolean"}},
+				"timeout": {"type": ["number", "null"], "exclusiveMinimum": 0},
+				"id": {"oneOf": [{"type": "string"}, {"type": "integer"}]}
+			},
+			"required": ["apiUrl", "retries"],
+			"additionalProperties": false
+		}
+	}
+}`
+
+func TestJSONSchemaValidate(t *testing.T) {
+	schema, err := parseJSONSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("parseJSONSchema() returned an unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name string
+		conf any
+		want []SchemaViolation
+	}{
+		{
+			name: "valid",
+			conf: map[string]any{
+				"apiUrl":  "https://api.example.com",
+				"retries": 3,
+				"mode":    "dark",
+				"tags":    []string{"a", "b"},
+				"flags":   map[string]bool{"beta": true},
+				"timeout": nil,
+				"id":      42,
+			},
+		},
+		{
+			name: "missing required",
+			conf: map[string]any{"apiUrl": "https://api.example.com"},
+			want: []SchemaViolation{{Path: "", Message: `missing required property "retries"`}},
+		},
+		{
+			name: "every violation is listed",
+			conf: map[string]any{
+				"apiUrl":  "http://api.example.com",
+				"retries": 1.5,
+				"mode":    "blue",
+				"tags":    []string{"a", "", "a"},
+				"flags":   map[string]any{"beta": "yes"},
+				"timeout": 0,
+				"unknown": true,
+			},
+			want: []SchemaViolation{
+				{Path: "/apiUrl", Message: "value must match pattern ^https://"},
+				{Path: "/flags/beta", Message: "expected type boolean but got string"},
+				{Path: "/mode", Message: `value must be one of ["light","dark"]`},
+				{Path: "/retries", Message: "expected type integer but got number"},
+				{Path: "/tags", Message: "items 0 and 2 must be unique"},
+				{Path: "/tags/1", Message: "length must be at least 1"},
+				{Path: "/timeout", Message: "value must be greater than 0"},
+				{Path: "", Message: `additional property "unknown" is not allowed`},
+			},
+		},
+		{
+			name: "oneOf",
+			conf: map[string]any{"apiUrl": "https://a", "retries": 0, "id": true},
+			want: []SchemaViolation{{Path: "/id", Message: "value must match exactly one schema of oneOf but matches 0"}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := schema.validate(tc.conf)
+			if err != nil {
+				t.Fatalf("validate() returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("validate() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONSchemaKeywords(t *testing.T) {
+	tt := []struct {
+		name   string
+		schema string
+		conf   any
+		want   []SchemaViolation
+	}{
+		{
+			name:   "format is an annotation",
+			schema: `{"properties": {"url": {"type": "string", "format": "uri"}}}`,
+			conf:   map[string]any{"url": "not a uri"},
+		},
+		{
+			name:   "minProperties",
+			schema: `{"properties": {"flags": {"minProperties": 1}}}`,
+			conf:   map[string]any{"flags": map[string]any{}},
+			want:   []SchemaViolation{{Path: "/flags", Message: "must have at least 1 properties"}},
+		},
+		{
+			name:   "maxProperties",
+			schema: `{"maxProperties": 1}`,
+			conf:   map[string]any{"a": 1, "b": 2},
+			want:   []SchemaViolation{{Path: "", Message: "must have at most 1 properties"}},
+		},
+		{
+			name:   "propertyNames",
+			schema: `{"propertyNames": {"pattern": "^[a-z]+$"}}`,
+			conf:   map[string]any{"api": 1, "API_URL": 2},
+			want:   []SchemaViolation{{Path: "", Message: `property name "API_URL" does not match the schema of propertyNames`}},
+		},
+		{
+			name:   "contains",
+			schema: `{"properties": {"tags": {"contains": {"const": "stable"}}}}`,
+			conf:   map[string]any{"tags": []string{"beta", "rc"}},
+			want:   []SchemaViolation{{Path: "/tags", Message: "must contain an item matching the schema of contains"}},
+		},
+		{
+			name:   "contains matches",
+			schema: `{"properties": {"tags": {"contains": {"const": "stable"}}}}`,
+			conf:   map[string]any{"tags": []string{"beta", "stable"}},
+		},
+		{
+			name:   "dependentRequired",
+			schema: `{"dependentRequired": {"clientId": ["authUrl"], "unused": ["other"]}}`,
+			conf:   map[string]any{"clientId": "spa"},
+			want:   []SchemaViolation{{Path: "", Message: `missing property "authUrl" required by "clientId"`}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			schema, err := parseJSONSchema([]byte(tc.schema))
+			if err != nil {
+				t.Fatalf("parseJSONSchema() returned an unexpected error: %v", err)
+			}
+			got, err := schema.validate(tc.conf)
+			if err != nil {
+				t.Fatalf("validate() returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("validate() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONSchemaErrors(t *testing.T) {
+	tt := map[string]string{
+		"invalid json":            `{`,
+		"invalid pattern":         `{"properties": {"a": {"pattern": "("}}}`,
+		"if then else":            `{"if": {"type": "string"}, "then": {"minLength": 1}}`,
+		"dependencies":            `{"dependencies": {"a": ["b"]}}`,
+		"prefixItems":             `{"prefixItems": [{"type": "string"}]}`,
+		"unevaluatedProperties":   `{"definitions": {"a": {"unevaluatedProperties": false}}}`,
+		"draft-04 exclusive min":  `{"minimum": 0, "exclusiveMinimum": true}`,
+		"nested in combinator":    `{"anyOf": [{"type": "string"}, {"not": {"if": {"type": "string"}}}]}`,
+		"nested in contains":      `{"contains": {"minContains": 1}}`,
+		"nested in propertyNames": `{"propertyNames": {"pattern": "("}}`,
+	}
+
+	for name, schema := range tt {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseJSONSchema([]byte(schema)); !errors.Is(err, ErrCouldNotParseSchema) {
+				t.Errorf("parseJSONSchema() error = %v, want %v", err, ErrCouldNotParseSchema)
+			}
+		})
+	}
+
+	t.Run("unresolvable ref", func(t *testing.T) {
+		schema, _ := parseJSONSchema([]byte(`{"$ref": "#/definitions/Missing"}`))
+		if _, err := schema.validate(map[string]any{}); !errors.Is(err, ErrCouldNotParseSchema) {
+			t.Errorf("validate() error = %v, want %v", err, ErrCouldNotParseSchema)
+		}
+	})
+
+	t.Run("cyclic ref", func(t *testing.T) {
+		schema, _ := parseJSONSchema([]byte(`{"$ref": "#"}`))
+		if _, err := schema.validate(map[string]any{}); !errors.Is(err, ErrCouldNotParseSchema) {
+			t.Errorf("validate() error = %v, want %v", err, ErrCouldNotParseSchema)
+		}
+	})
+}
+
+func TestInjectWebEnvWithSchema(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+	_ = fsys.WriteFile("env.schema.json", []byte(testSchema), 0644)
+
+	if _, err := InjectWebEnv(fsys, map[string]any{"apiUrl": "https://a", "retries": 1}, "APP_ENV", WithSchema("env.schema.json")); err != nil {
+		t.Errorf("InjectWebEnv() returned an unexpected error: %v", err)
+	}
+
+	_, err := InjectWebEnv(fsys, map[string]any{"apiUrl": "http://a"}, "APP_ENV", WithSchema("env.schema.json"))
+	var schemaErr *SchemaValidationError
+	if !errors.As(err, &schemaErr) || len(schemaErr.Violations) != 2 || schemaErr.Schema != "env.schema.json" {
+		t.Errorf("InjectWebEnv() error = %v, want 2 violations", err)
+	}
+	if !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("InjectWebEnv() error = %v, want %v", err, ErrSchemaViolation)
+	}
+
+	if _, err := NewStaticFilesHandler(fsys, WithInjectWebEnv(map[string]any{}, "", WithSchema("missing.json"))); !errors.Is(err, ErrCouldNotReadFile) {
+		t.Errorf("NewStaticFilesHandler() error = %v, want %v", err, ErrCouldNotReadFile)
+	}
+}
@@ -0,0 +1,117 @@
+package spaserve
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MultiAppHandler serves several single page applications from one handler and dispatches requests to the app
+// with the longest matching base path.
+type MultiAppHandler struct {
+	apps     []mountedApp
+	notFound http.Handler
+}
+
+// mountedApp is an app handler with its normalized base path
+type mountedApp struct {
+	basePath string
+	handler  *StaticFilesHandler
+}
+
+type multiAppHandlerOpts struct {
+	apps     []multiAppConfig
+	notFound http.Handler
+}
+
+// multiAppConfig is the configuration of a single app before its handler is created
+type multiAppConfig struct {
+	basePath string
+	filesys  fs.FS
+	fn       []staticFilesHandlerFunc
+}
+
+type multiAppHandlerFunc func(multiAppHandlerOpts) multiAppHandlerOpts
+
+// WithApp mounts a single page application under the given base path.
+//
+//	basePath: the base path of the app, it is set with WithBasePath and overrides any base path in fn
+//	filesys: the file system to serve the app from
+//	fn: optional functions to configure the app handler (e.g. WithInjectWebEnv, WithMuxErrorHandler)
+func WithApp(basePath string, filesys fs.FS, fn ...staticFilesHandlerFunc) multiAppHandlerFunc {
+	return func(c multiAppHandlerOpts) multiAppHandlerOpts {
+		c.apps = append(c.apps, multiAppConfig{
+			basePath: normalizeBasePath(basePath),
+			filesys:  filesys,
+			fn:       fn,
+		})
+		return c
+	}
+}
+
+// WithNotFoundHandler sets the handler for requests which match no app. Defaults to http.NotFoundHandler.
+func WithNotFoundHandler(handler http.Handler) multiAppHandlerFunc {
+	return func(c multiAppHandlerOpts) multiAppHandlerOpts {
+		c.notFound = handler
+		return c
+	}
+}
+
+// NewMultiAppHandler creates a handler which serves every app mounted with WithApp.
+// Each app gets its own StaticFilesHandler, so index fallback, web env and error handling are configured per app.
+//   - fn: functions to configure the handler (e.g. WithApp, WithNotFoundHandler)
+func NewMultiAppHandler(fn ...multiAppHandlerFunc) (*MultiAppHandler, error) {
+	// process options
+	opts := multiAppHandlerOpts{notFound: http.NotFoundHandler()}
+	for _, f := range fn {
+		opts = f(opts)
+	}
+
+	if len(opts.apps) == 0 {
+		return nil, ErrNoApps
+	}
+
+	seen := map[string]bool{}
+	apps := make([]mountedApp, 0, len(opts.apps))
+	for _, app := range opts.apps {
+		if seen[app.basePath] {
+			return nil, errors.Join(ErrDuplicateBasePath, errors.New(app.basePath))
+		}
+		seen[app.basePath] = true
+
+		handler, err := NewStaticFilesHandler(app.filesys, append(app.fn[:len(app.fn):len(app.fn)], WithBasePath(app.basePath))...)
+		if err != nil {
+			return nil, errors.Join(ErrCouldNotCreateApp, errors.New(app.basePath), err)
+		}
+
+		apps = append(apps, mountedApp{basePath: app.basePath, handler: handler})
+	}
+
+	// sort by base path length so the longest prefix matches first
+	sort.SliceStable(apps, func(i, j int) bool {
+		return len(apps[i].basePath) > len(apps[j].basePath)
+	})
+
+	return &MultiAppHandler{
+		apps:     apps,
+		notFound: opts.notFound,
+	}, nil
+}
+
+func (h *MultiAppHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, app := range h.apps {
+		if matchBasePath(app.basePath, r.URL.Path) {
+			app.handler.ServeHTTP(w, r)
+			return
+		}
+	}
+
+	h.notFound.ServeHTTP(w, r)
+}
+
+// matchBasePath returns true if the request path is within the base path, the base path without trailing slash matches too
+func matchBasePath(basePath string, p string) bool {
+	return strings.HasPrefix(p, basePath) || p == strings.TrimSuffix(basePath, "/")
+}
@@ -0,0 +1,88 @@
+package spaserve
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestMultiAppHandler(t *testing.T) {
+	newFilesys := func(name string) *memfs.FS {
+		mfs := memfs.New()
+		_ = mfs.WriteFile("index.html", []byte("<html><head></head><body>"+name+"</body></html>"), 0644)
+		_ = mfs.WriteFile(name+".js", []byte("console.log('"+name+"');"), 0644)
+		return mfs
+	}
+
+	customNotFound := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	handler, err := NewMultiAppHandler(
+		WithApp("/app", newFilesys("customer"), WithInjectWebEnv(map[string]string{"app": "customer"}, "CUSTOMER_ENV")),
+		WithApp("/app/admin", newFilesys("admin"), WithInjectWebEnv(map[string]string{"app": "admin"}, "ADMIN_ENV")),
+		WithNotFoundHandler(customNotFound),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{name: "customer index", path: "/app/", wantCode: http.StatusOK, wantBody: "CUSTOMER_ENV"},
+		{name: "customer without trailing slash", path: "/app", wantCode: http.StatusOK, wantBody: "CUSTOMER_ENV"},
+		{name: "customer fallback", path: "/app/orders/1", wantCode: http.StatusOK, wantBody: "CUSTOMER_ENV"},
+		{name: "customer asset", path: "/app/customer.js", wantCode: http.StatusOK, wantBody: "customer"},
+		{name: "admin by longest prefix", path: "/app/admin/users", wantCode: http.StatusOK, wantBody: "ADMIN_ENV"},
+		{name: "admin asset", path: "/app/admin/admin.js", wantCode: http.StatusOK, wantBody: "admin"},
+		{name: "admin missing asset", path: "/app/admin/customer.js", wantCode: http.StatusNotFound, wantBody: ""},
+		{name: "no app", path: "/other", wantCode: http.StatusTeapot, wantBody: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("Expected status code %d, but got %d", tc.wantCode, w.Code)
+			}
+
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("Expected body to contain %q, but got %q", tc.wantBody, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("rejects no apps", func(t *testing.T) {
+		if _, err := NewMultiAppHandler(); !errors.Is(err, ErrNoApps) {
+			t.Errorf("Expected ErrNoApps, but got %v", err)
+		}
+	})
+
+	t.Run("rejects duplicate base paths", func(t *testing.T) {
+		_, err := NewMultiAppHandler(
+			WithApp("admin", newFilesys("a")),
+			WithApp("/admin/", newFilesys("b")),
+		)
+		if !errors.Is(err, ErrDuplicateBasePath) {
+			t.Errorf("Expected ErrDuplicateBasePath, but got %v", err)
+		}
+	})
+
+	t.Run("returns app errors", func(t *testing.T) {
+		_, err := NewMultiAppHandler(WithApp("/", memfs.New(), WithInjectWebEnv(map[string]string{}, "")))
+		if !errors.Is(err, ErrCouldNotCreateApp) || !errors.Is(err, ErrNoIndexFound) {
+			t.Errorf("Expected ErrCouldNotCreateApp and ErrNoIndexFound, but got %v", err)
+		}
+	})
+}
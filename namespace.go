@@ -0,0 +1,66 @@
+package spaserve
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var namespaceRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedNamespaces are the javascript reserved words and the globals and properties which must not be
+// overwritten by a namespace segment
+var reservedNamespaces = map[string]bool{
+	// globals and object internals
+	"window": true, "document": true, "globalThis": true, "self": true, "top": true, "parent": true,
+	"location": true, "__proto__": true, "prototype": true, "constructor": true,
+	// reserved words
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true, "debugger": true,
+	"default": true, "delete": true, "do": true, "else": true, "enum": true, "export": true, "extends": true,
+	"false": true, "finally": true, "for": true, "function": true, "if": true, "implements": true, "import": true,
+	"in": true, "instanceof": true, "interface": true, "let": true, "new": true, "null": true, "package": true,
+	"private": true, "protected": true, "public": true, "return": true, "static": true, "super": true,
+	"switch": true, "this": true, "throw": true, "true": true, "try": true, "typeof": true, "var": true,
+	"void": true, "while": true, "with": true, "yield": true, "await": true, "undefined": true, "NaN": true,
+	"Infinity": true,
+}
+
+// validateNamespace returns the trimmed namespace or an error if it is empty or invalid.
+// A namespace is a dotted path (e.g. __SHELL__.apps.billing) and each segment is validated on its own.
+func validateNamespace(ns string) (string, error) {
+	if ns == "" {
+		return "", ErrNoNamespace
+	}
+	ns = strings.TrimSpace(ns)
+	for _, segment := range strings.Split(ns, ".") {
+		if !namespaceRegex.MatchString(segment) {
+			return "", ErrCouldNotParseNamespace
+		}
+		if reservedNamespaces[segment] {
+			return "", errors.Join(ErrReservedNamespace, errors.New(segment))
+		}
+	}
+	return ns, nil
+}
+
+// namespacesCollide returns true if the namespaces are equal or one is a parent path of the other,
+// as assigning the parent would overwrite the child or the child would be added to the parent
+func namespacesCollide(a string, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
+
+// namespaceAssignment returns the javascript statements assigning the value to the namespace of the root object.
+// The intermediate objects of a dotted namespace are created unless they exist, so they are never clobbered
+// (e.g. window.a = window.a || {}; window.a.b = {...};).
+func namespaceAssignment(root string, ns string, value string) string {
+	segments := strings.Split(ns, ".")
+
+	var b strings.Builder
+	target := root
+	for _, segment := range segments[:len(segments)-1] {
+		target += "." + segment
+		b.WriteString(target + " = " + target + " || {}; ")
+	}
+	b.WriteString(target + "." + segments[len(segments)-1] + " = " + value + ";")
+	return b.String()
+}
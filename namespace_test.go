@@ -0,0 +1,90 @@
+package spaserve
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateNamespace(t *testing.T) {
+	tt := []struct {
+		ns      string
+		want    string
+		wantErr error
+	}{
+		{ns: "APP_ENV", want: "APP_ENV"},
+		{ns: " __SHELL__.apps.billing ", want: "__SHELL__.apps.billing"},
+		{ns: "", wantErr: ErrNoNamespace},
+		{ns: "a..b", wantErr: ErrCouldNotParseNamespace},
+		{ns: ".a", wantErr: ErrCouldNotParseNamespace},
+		{ns: "a.1b", wantErr: ErrCouldNotParseNamespace},
+		{ns: "a-b", wantErr: ErrCouldNotParseNamespace},
+		{ns: "window.APP_ENV", wantErr: ErrReservedNamespace},
+		{ns: "document", wantErr: ErrReservedNamespace},
+		{ns: "a.__proto__.b", wantErr: ErrReservedNamespace},
+		{ns: "a.constructor", wantErr: ErrReservedNamespace},
+		{ns: "a.class", wantErr: ErrReservedNamespace},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ns, func(t *testing.T) {
+			got, err := validateNamespace(tc.ns)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("validateNamespace() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("validateNamespace() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceAssignment(t *testing.T) {
+	tt := []struct {
+		root string
+		ns   string
+		want string
+	}{
+		{
+			root: "window",
+			ns:   "APP_ENV",
+			want: `window.APP_ENV = {"a":1};`,
+		},
+		{
+			root: "window",
+			ns:   "__SHELL__.apps.billing",
+			want: `window.__SHELL__ = window.__SHELL__ || {}; window.__SHELL__.apps = window.__SHELL__.apps || {}; window.__SHELL__.apps.billing = {"a":1};`,
+		},
+		{
+			root: "globalThis",
+			ns:   "a.b",
+			want: `globalThis.a = globalThis.a || {}; globalThis.a.b = {"a":1};`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ns, func(t *testing.T) {
+			if got := namespaceAssignment(tc.root, tc.ns, `{"a":1}`); got != tc.want {
+				t.Errorf("namespaceAssignment() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNamespacesCollide(t *testing.T) {
+	tt := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "APP_ENV", b: "APP_ENV", want: true},
+		{a: "APP_ENV", b: "APP_ENV.flags", want: true},
+		{a: "__SHELL__.apps.billing", b: "__SHELL__", want: true},
+		{a: "__SHELL__.apps.billing", b: "__SHELL__.apps.orders", want: false},
+		{a: "APP", b: "APP_ENV", want: false},
+	}
+
+	for _, tc := range tt {
+		if got := namespacesCollide(tc.a, tc.b); got != tc.want {
+			t.Errorf("namespacesCollide(%q, %q) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package spaserve
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type osWebEnvOpts struct {
+	environ     []string
+	stripPrefix bool
+	camelCase   bool
+	typed       bool
+}
+
+type osWebEnvFunc func(osWebEnvOpts) osWebEnvOpts
+
+// WithStripPrefix removes the prefix from the keys (e.g. PUBLIC_API_URL becomes API_URL).
+func WithStripPrefix() osWebEnvFunc {
+	return func(c osWebEnvOpts) osWebEnvOpts {
+		c.stripPrefix = true
+		return c
+	}
+}
+
+// WithCamelCaseKeys converts the keys from SCREAMING_SNAKE_CASE to camelCase (e.g. API_URL becomes apiUrl).
+func WithCamelCaseKeys() osWebEnvFunc {
+	return func(c osWebEnvOpts) osWebEnvOpts {
+		c.camelCase = true
+		return c
+	}
+}
+
+// WithTypedValues coerces "true" and "false" to booleans and numbers to numbers, values which would not
+// marshal back to the same string (e.g. "007" or "1.50"), are not finite (e.g. "NaN") or are integers javascript
+// can not represent exactly (beyond ±(2^53-1)) are kept as strings.
+func WithTypedValues() osWebEnvFunc {
+	return func(c osWebEnvOpts) osWebEnvOpts {
+		c.typed = true
+		return c
+	}
+}
+
+// WithEnviron reads the variables from the given "KEY=value" list instead of os.Environ.
+func WithEnviron(environ []string) osWebEnvFunc {
+	return func(c osWebEnvOpts) osWebEnvOpts {
+		c.environ = environ
+		return c
+	}
+}
+
+// WebEnvFromOS collects the environment variables matching the prefix into a web environment which can be passed to
+// InjectWebEnv or WithInjectWebEnv like any other config value.
+//   - prefix: the prefix the variables must start with (e.g. PUBLIC_ or VITE_)
+//   - fn: optional functions to configure the collection (e.g. WithStripPrefix, WithCamelCaseKeys, WithTypedValues)
+func WebEnvFromOS(prefix string, fn ...osWebEnvFunc) (map[string]any, error) {
+	// process options
+	opts := osWebEnvOpts{}
+	for _, f := range fn {
+		opts = f(opts)
+	}
+	if opts.environ == nil {
+		opts.environ = os.Environ()
+	}
+
+	env := map[string]any{}
+	for _, kv := range opts.environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		if opts.stripPrefix {
+			key = strings.TrimPrefix(key, prefix)
+		}
+		if opts.camelCase {
+			key = camelCase(key)
+		}
+		if key == "" {
+			continue
+		}
+
+		if _, ok := env[key]; ok {
+			return nil, errors.Join(ErrDuplicateWebEnvKey, errors.New(key))
+		}
+
+		if opts.typed {
+			env[key] = coerceValue(value)
+		} else {
+			env[key] = value
+		}
+	}
+
+	return env, nil
+}
+
+// camelCase converts a SCREAMING_SNAKE_CASE key to camelCase
+func camelCase(key string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(strings.ToLower(key), "_") {
+		if part == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			part = strings.ToUpper(part[:1]) + part[1:]
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// maxSafeInteger is the largest integer javascript numbers represent exactly (Number.MAX_SAFE_INTEGER)
+const maxSafeInteger = 1<<53 - 1
+
+// coerceValue returns the value as bool or number if it round trips to the same string
+// and javascript represents it exactly
+func coerceValue(value string) any {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(i, 10) == value {
+		if i > maxSafeInteger || i < -maxSafeInteger {
+			return value
+		}
+		return i
+	}
+
+	// json can not marshal NaN and Inf, larger floats are integers beyond the safe range
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value {
+		if math.IsNaN(f) || math.Abs(f) > maxSafeInteger {
+			return value
+		}
+		return f
+	}
+
+	return value
+}
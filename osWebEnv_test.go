@@ -0,0 +1,111 @@
+package spaserve
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestWebEnvFromOS(t *testing.T) {
+	environ := []string{
+		"PUBLIC_API_URL=https://api.example.com",
+		"PUBLIC_FEATURE_ENABLED=true",
+		"PUBLIC_MAX_ITEMS=25",
+		"PUBLIC_RATIO=0.5",
+		"PUBLIC_ZIP=007",
+		"PUBLIC_EMPTY=",
+		"SECRET_TOKEN=hunter2",
+		"PATH=/usr/bin",
+	}
+
+	tt := []struct {
+		name string
+		fn   []osWebEnvFunc
+		want string
+	}{
+		{
+			name: "keeps keys and strings",
+			fn:   nil,
+			want: `{"PUBLIC_API_URL":"https://api.example.com","PUBLIC_EMPTY":"","PUBLIC_FEATURE_ENABLED":"true","PUBLIC_MAX_ITEMS":"25","PUBLIC_RATIO":"0.5","PUBLIC_ZIP":"007"}`,
+		},
+		{
+			name: "strips prefix",
+			fn:   []osWebEnvFunc{WithStripPrefix()},
+			want: `{"API_URL":"https://api.example.com","EMPTY":"","FEATURE_ENABLED":"true","MAX_ITEMS":"25","RATIO":"0.5","ZIP":"007"}`,
+		},
+		{
+			name: "camel case keys",
+			fn:   []osWebEnvFunc{WithCamelCaseKeys()},
+			want: `{"publicApiUrl":"https://api.example.com","publicEmpty":"","publicFeatureEnabled":"true","publicMaxItems":"25","publicRatio":"0.5","publicZip":"007"}`,
+		},
+		{
+			name: "strips prefix, camel case keys and typed values",
+			fn:   []osWebEnvFunc{WithStripPrefix(), WithCamelCaseKeys(), WithTypedValues()},
+			want: `{"apiUrl":"https://api.example.com","empty":"","featureEnabled":true,"maxItems":25,"ratio":0.5,"zip":"007"}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			env, err := WebEnvFromOS("PUBLIC_", append(tc.fn, WithEnviron(environ))...)
+			if err != nil {
+				t.Fatalf("WebEnvFromOS() returned an unexpected error: %v", err)
+			}
+
+			b, err := json.Marshal(env)
+			if err != nil {
+				t.Fatalf("json.Marshal() returned an unexpected error: %v", err)
+			}
+
+			if got := string(b); got != tc.want {
+				t.Errorf("WebEnvFromOS() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("reads os environment", func(t *testing.T) {
+		t.Setenv("SPASERVE_TEST_NAME", "test")
+
+		env, err := WebEnvFromOS("SPASERVE_TEST_", WithStripPrefix())
+		if err != nil {
+			t.Fatalf("WebEnvFromOS() returned an unexpected error: %v", err)
+		}
+
+		if got := env["NAME"]; got != "test" {
+			t.Errorf("WebEnvFromOS() NAME = %v, want %q", got, "test")
+		}
+	})
+
+	t.Run("keeps values javascript can not represent as strings", func(t *testing.T) {
+		env, err := WebEnvFromOS("PUBLIC_", WithStripPrefix(), WithTypedValues(), WithEnviron([]string{
+			"PUBLIC_NAN=NaN",
+			"PUBLIC_INF=Inf",
+			"PUBLIC_PLUS_INF=+Inf",
+			"PUBLIC_MINUS_INF=-Inf",
+			"PUBLIC_BIG=9007199254740993",
+			"PUBLIC_BIG_NEGATIVE=-9007199254740993",
+			"PUBLIC_BIG_FLOAT=18446744073709551616",
+			"PUBLIC_SAFE=9007199254740991",
+		}))
+		if err != nil {
+			t.Fatalf("WebEnvFromOS() returned an unexpected error: %v", err)
+		}
+
+		b, err := json.Marshal(env)
+		if err != nil {
+			t.Fatalf("json.Marshal() returned an unexpected error: %v", err)
+		}
+
+		want := `{"BIG":"9007199254740993","BIG_FLOAT":"18446744073709551616","BIG_NEGATIVE":"-9007199254740993","INF":"Inf","MINUS_INF":"-Inf","NAN":"NaN","PLUS_INF":"+Inf","SAFE":9007199254740991}`
+		if got := string(b); got != want {
+			t.Errorf("WebEnvFromOS() = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("rejects duplicate keys", func(t *testing.T) {
+		_, err := WebEnvFromOS("PUBLIC_", WithStripPrefix(), WithCamelCaseKeys(), WithEnviron([]string{"PUBLIC_API_URL=a", "PUBLIC_API__URL=b"}))
+		if !errors.Is(err, ErrDuplicateWebEnvKey) {
+			t.Errorf("WebEnvFromOS() error = %v, want %v", err, ErrDuplicateWebEnvKey)
+		}
+	})
+}
@@ -0,0 +1,272 @@
+package spaserve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultPlaceholderPrefix is the prefix of placeholders (e.g. __SPASERVE_API_URL__) if none is provided
+const DefaultPlaceholderPrefix = "__SPASERVE_"
+
+// placeholderSuffix terminates every placeholder
+const placeholderSuffix = "__"
+
+// placeholderExts are the file extensions placeholders are replaced in
+var placeholderExts = map[string]bool{
+	".js":   true,
+	".mjs":  true,
+	".json": true,
+	".css":  true,
+	".html": true,
+}
+
+// PlaceholderReport lists the placeholders without a value and the values which no placeholder uses.
+type PlaceholderReport struct {
+	// Missing are the placeholders found in files which have no value, they are left untouched
+	Missing []string
+	// Unused are the placeholders of values which are not found in any file
+	Unused []string
+}
+
+// PlaceholderReplacer replaces placeholders in js, json, css and html files with the top-level values of a config.
+// The placeholder of a value is the prefix, the upper snake case json key and "__" (e.g. apiUrl becomes
+// __SPASERVE_API_URL__). In javascript, json and inline scripts of html documents a placeholder which is a whole string
+// literal is replaced by the JSON literal of the value, any other placeholder is escaped as string content. Values are
+// html escaped in the rest of html documents and inserted as text in css.
+// Use Hook as the OnHookFunc of CopyFileSys and Report once all files are copied.
+type PlaceholderReplacer struct {
+	regex  *regexp.Regexp
+	values map[string]json.RawMessage
+
+	mu      sync.Mutex
+	used    map[string]bool
+	missing map[string]bool
+}
+
+// NewPlaceholderReplacer creates a replacer for the top-level values of the config.
+//   - conf: the config providing the values, use json struct tags to drive the marshalling
+//   - prefix: the prefix of the placeholders, defaults to DefaultPlaceholderPrefix
+func NewPlaceholderReplacer(conf any, prefix string) (*PlaceholderReplacer, error) {
+	if prefix == "" {
+		prefix = DefaultPlaceholderPrefix
+	}
+
+	// placeholders end up in public assets just like the web env
+	if _, err := checkSecrets(conf); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, ErrConfigNotAnObject
+	}
+
+	values := map[string]json.RawMessage{}
+	for key, value := range fields {
+		name := prefix + upperSnakeCase(key) + placeholderSuffix
+		if _, ok := values[name]; ok {
+			return nil, errors.Join(ErrDuplicatePlaceholder, errors.New(name))
+		}
+		values[name] = value
+	}
+
+	return &PlaceholderReplacer{
+		regex:   regexp.MustCompile(`(["']?)` + regexp.QuoteMeta(prefix) + `[A-Z0-9]+(?:_[A-Z0-9]+)*` + regexp.QuoteMeta(placeholderSuffix) + `(["']?)`),
+		values:  values,
+		used:    map[string]bool{},
+		missing: map[string]bool{},
+	}, nil
+}
+
+// Hook replaces the placeholders of the file, it can be used as OnHookFunc.
+func (p *PlaceholderReplacer) Hook(name string, data []byte) ([]byte, error) {
+	ext := strings.ToLower(path.Ext(name))
+	if !placeholderExts[ext] {
+		return data, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch ext {
+	case ".css":
+		return p.replaceAll(data, func(m placeholderMatch, value json.RawMessage) []byte {
+			return []byte(m.openQuote + escapeCSSStringContent(placeholderText(value)) + m.closeQuote)
+		}), nil
+	case ".html":
+		// inline scripts are javascript, everything else is html
+		scripts, err := scriptRanges(data)
+		if err != nil {
+			return nil, err
+		}
+		literals := jsLiterals{strings: map[int]int{}}
+		for _, r := range scripts {
+			literals = literals.add(lexJSLiterals(data[r[0]:r[1]]), r[0])
+		}
+		return p.replaceAll(data, func(m placeholderMatch, value json.RawMessage) []byte {
+			for _, r := range scripts {
+				if m.start >= r[0] && m.end <= r[1] {
+					return replaceJSPlaceholder(m, value, literals)
+				}
+			}
+			return []byte(m.openQuote + html.EscapeString(placeholderText(value)) + m.closeQuote)
+		}), nil
+	}
+
+	literals := lexJSLiterals(data)
+	return p.replaceAll(data, func(m placeholderMatch, value json.RawMessage) []byte {
+		return replaceJSPlaceholder(m, value, literals)
+	}), nil
+}
+
+// placeholderMatch is a placeholder found in a file together with the quotes directly around it
+type placeholderMatch struct {
+	start       int
+	end         int
+	openQuote   string
+	closeQuote  string
+	placeholder string
+}
+
+// replaceAll replaces every placeholder with a value by the result of replace and records the used and missing
+// placeholders, the caller has to hold the lock
+func (p *PlaceholderReplacer) replaceAll(data []byte, replace func(placeholderMatch, json.RawMessage) []byte) []byte {
+	var b bytes.Buffer
+	last := 0
+	for _, loc := range p.regex.FindAllSubmatchIndex(data, -1) {
+		m := placeholderMatch{
+			start:      loc[0],
+			end:        loc[1],
+			openQuote:  string(data[loc[2]:loc[3]]),
+			closeQuote: string(data[loc[4]:loc[5]]),
+		}
+		m.placeholder = string(data[loc[0]+len(m.openQuote) : loc[1]-len(m.closeQuote)])
+
+		value, ok := p.values[m.placeholder]
+		if !ok {
+			p.missing[m.placeholder] = true
+			continue
+		}
+		p.used[m.placeholder] = true
+
+		b.Write(data[last:m.start])
+		b.Write(replace(m, value))
+		last = m.end
+	}
+	if last == 0 {
+		return data
+	}
+	b.Write(data[last:])
+	return b.Bytes()
+}
+
+// replaceJSPlaceholder returns the javascript replacing the placeholder. A placeholder which is a whole string literal
+// or which is not inside a string literal at all is replaced by the JSON literal, so non-string values keep their type
+// and strings are never inserted as code. Otherwise it is escaped as string content.
+func replaceJSPlaceholder(m placeholderMatch, value json.RawMessage, literals jsLiterals) []byte {
+	if m.openQuote != "" && m.openQuote == m.closeQuote && literals.isWholeString(m.start, m.end) {
+		return value
+	}
+	if m.openQuote == "" && !literals.inString(m.start) {
+		// a quote directly after the placeholder starts the next string literal
+		return []byte(string(value) + m.closeQuote)
+	}
+	return []byte(m.openQuote + escapeJSStringContent(value) + m.closeQuote)
+}
+
+// Report returns the placeholders without a value and the values which no placeholder used so far.
+func (p *PlaceholderReplacer) Report() PlaceholderReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	report := PlaceholderReport{}
+	for placeholder := range p.missing {
+		report.Missing = append(report.Missing, placeholder)
+	}
+	for placeholder := range p.values {
+		if !p.used[placeholder] {
+			report.Unused = append(report.Unused, placeholder)
+		}
+	}
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unused)
+	return report
+}
+
+// placeholderText returns the value as text, strings are unquoted and other values are JSON
+func placeholderText(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+// escapeJSStringContent returns the value escaped to be embedded in any javascript string literal
+func escapeJSStringContent(value json.RawMessage) string {
+	b, _ := json.Marshal(placeholderText(value))
+	b = bytes.TrimSuffix(bytes.TrimPrefix(b, []byte(`"`)), []byte(`"`))
+	return strings.NewReplacer("'", `\u0027`, "`", `\u0060`, "$", `\u0024`).Replace(string(b))
+}
+
+// escapeCSSStringContent returns the text escaped to be embedded in any css string
+func escapeCSSStringContent(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '"' || r == '\'':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			// control characters including newlines are escaped as hex code points terminated by a space
+			b.WriteString(`\` + strconv.FormatInt(int64(r), 16) + " ")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// upperSnakeCase converts a key to UPPER_SNAKE_CASE (e.g. apiUrl and api-url become API_URL)
+func upperSnakeCase(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == '.' || r == ' ':
+			b.WriteRune('_')
+			continue
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])):
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// logPlaceholderReport logs a warning for every missing and unused placeholder
+func logPlaceholderReport(logger *servespaLogger, report PlaceholderReport) {
+	ctx := context.Background()
+	for _, placeholder := range report.Missing {
+		logger.logContext(ctx, slog.LevelWarn, "placeholder has no value", slog.Attr{Key: "placeholder", Value: slog.StringValue(placeholder)})
+	}
+	for _, placeholder := range report.Unused {
+		logger.logContext(ctx, slog.LevelWarn, "value is not used by any placeholder", slog.Attr{Key: "placeholder", Value: slog.StringValue(placeholder)})
+	}
+}
@@ -0,0 +1,225 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestPlaceholderReplacer(t *testing.T) {
+	conf := struct {
+		APIURL  string         `json:"apiUrl"`
+		Title   string         `json:"title"`
+		Retries int            `json:"retries"`
+		Debug   bool           `json:"debug"`
+		Flags   map[string]int `json:"flags"`
+		Unused  string         `json:"unused"`
+	}{
+		APIURL:  "https://api.example.com/?a=1&b='2'",
+		Title:   `Tom's "App" <beta>`,
+		Retries: 3,
+		Debug:   true,
+		Flags:   map[string]int{"a": 1},
+		Unused:  "unused",
+	}
+
+	tt := []struct {
+		name string
+		path string
+		data string
+		want string
+	}{
+		{
+			name: "quoted placeholder is replaced by the JSON literal",
+			path: "assets/app.js",
+			data: `const url = "__SPASERVE_API_URL__", retries = '__SPASERVE_RETRIES__', debug = "__SPASERVE_DEBUG__";`,
+			want: `const url = "https://api.example.com/?a=1\u0026b='2'", retries = 3, debug = true;`,
+		},
+		{
+			name: "embedded placeholder is escaped for any string literal",
+			path: "assets/app.js",
+			data: "const title = 'Title: __SPASERVE_TITLE__', tpl = `${x} __SPASERVE_TITLE__`;",
+			want: "const title = 'Title: Tom\\u0027s \\\"App\\\" \\u003cbeta\\u003e', tpl = `${x} Tom\\u0027s \\\"App\\\" \\u003cbeta\\u003e`;",
+		},
+		{
+			name: "unquoted placeholder is replaced by the JSON literal",
+			path: "assets/app.js",
+			data: `const url = __SPASERVE_API_URL__, retries = __SPASERVE_RETRIES__, debug = __SPASERVE_DEBUG__;`,
+			want: `const url = "https://api.example.com/?a=1\u0026b='2'", retries = 3, debug = true;`,
+		},
+		{
+			name: "unquoted placeholder in an inline script is replaced by the JSON literal",
+			path: "index.html",
+			data: `<script>const title = __SPASERVE_TITLE__;</script>`,
+			want: `<script>const title = "Tom's \"App\" \u003cbeta\u003e";</script>`,
+		},
+		{
+			name: "objects are replaced by their JSON",
+			path: "config.json",
+			data: `{"flags": "__SPASERVE_FLAGS__"}`,
+			want: `{"flags": {"a":1}}`,
+		},
+		{
+			name: "css values are inserted as text",
+			path: "assets/app.css",
+			data: `.title::before { content: "__SPASERVE_RETRIES__"; }`,
+			want: `.title::before { content: "3"; }`,
+		},
+		{
+			name: "css values are escaped as string content",
+			path: "assets/app.css",
+			data: `.title::before { content: "__SPASERVE_TITLE__"; } .url { --url: '__SPASERVE_API_URL__'; }`,
+			want: `.title::before { content: "Tom\'s \"App\" <beta>"; } .url { --url: 'https://api.example.com/?a=1&b=\'2\''; }`,
+		},
+		{
+			name: "html values are escaped",
+			path: "index.html",
+			data: `<title>__SPASERVE_TITLE__</title>`,
+			want: `<title>Tom&#39;s &#34;App&#34; &lt;beta&gt;</title>`,
+		},
+		{
+			name: "html values are escaped for javascript in inline scripts",
+			path: "index.html",
+			data: `<script>const url = "__SPASERVE_API_URL__";</script><a href="__SPASERVE_API_URL__">`,
+			want: `<script>const url = "https://api.example.com/?a=1\u0026b='2'";</script><a href="https://api.example.com/?a=1&amp;b=&#39;2&#39;">`,
+		},
+		{
+			name: "placeholder nested in another string literal is escaped as string content",
+			path: "assets/app.js",
+			data: `const a = "url: '__SPASERVE_API_URL__'", b = 'retries: "__SPASERVE_RETRIES__"';`,
+			want: `const a = "url: 'https://api.example.com/?a=1\u0026b=\u00272\u0027'", b = 'retries: "3"';`,
+		},
+		{
+			name: "quotes in comments, templates and regular expressions do not start string literals",
+			path: "assets/app.js",
+			data: "/* \" */ const re = /[\"']/g, t = `\"${\"'\"}`; // '\nconst r = \"__SPASERVE_RETRIES__\";",
+			want: "/* \" */ const re = /[\"']/g, t = `\"${\"'\"}`; // '\nconst r = 3;",
+		},
+		{
+			name: "missing placeholders are left untouched",
+			path: "assets/app.js",
+			data: `const x = "__SPASERVE_MISSING__";`,
+			want: `const x = "__SPASERVE_MISSING__";`,
+		},
+		{
+			name: "other files are skipped",
+			path: "robots.txt",
+			data: `__SPASERVE_TITLE__`,
+			want: `__SPASERVE_TITLE__`,
+		},
+	}
+
+	replacer, err := NewPlaceholderReplacer(conf, "")
+	if err != nil {
+		t.Fatalf("NewPlaceholderReplacer() returned an unexpected error: %v", err)
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := replacer.Hook(tc.path, []byte(tc.data))
+			if err != nil {
+				t.Fatalf("Hook() returned an unexpected error: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("Hook() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("reports missing and unused placeholders", func(t *testing.T) {
+		want := PlaceholderReport{
+			Missing: []string{"__SPASERVE_MISSING__"},
+			Unused:  []string{"__SPASERVE_UNUSED__"},
+		}
+		if got := replacer.Report(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Report() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("rejects duplicate placeholders", func(t *testing.T) {
+		_, err := NewPlaceholderReplacer(map[string]string{"apiUrl": "a", "api_url": "b"}, "")
+		if !errors.Is(err, ErrDuplicatePlaceholder) {
+			t.Errorf("NewPlaceholderReplacer() error = %v, want %v", err, ErrDuplicatePlaceholder)
+		}
+	})
+
+	t.Run("rejects non object config", func(t *testing.T) {
+		if _, err := NewPlaceholderReplacer([]string{"a"}, ""); !errors.Is(err, ErrConfigNotAnObject) {
+			t.Errorf("NewPlaceholderReplacer() error = %v, want %v", err, ErrConfigNotAnObject)
+		}
+	})
+}
+
+func TestUpperSnakeCase(t *testing.T) {
+	tt := map[string]string{
+		"apiUrl":   "API_URL",
+		"api_url":  "API_URL",
+		"api-url":  "API_URL",
+		"API_URL":  "API_URL",
+		"oauth2Id": "OAUTH2_ID",
+	}
+
+	for key, want := range tt {
+		if got := upperSnakeCase(key); got != want {
+			t.Errorf("upperSnakeCase(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestStaticFilesHandlerWithPlaceholders(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte("<html><head><title>__SPASERVE_TITLE__</title></head><body></body></html>"), 0644)
+	_ = fsys.WriteFile("app.js", []byte(`fetch("__SPASERVE_API_URL__");`), 0644)
+	staleApp, _ := gzipData([]byte(`fetch("__SPASERVE_API_URL__");`))
+	_ = fsys.WriteFile("app.js.gz", staleApp, 0644)
+	_ = fsys.WriteFile("app.js.br", []byte("stale"), 0644)
+
+	logs := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+
+	handler, err := NewStaticFilesHandler(fsys,
+		WithLogger(logger),
+		WithPlaceholders(map[string]string{"title": "My App", "apiUrl": "https://api.example.com", "extra": "x"}, ""),
+		WithInjectWebEnv(map[string]string{"name": "test"}, ""),
+		WithPrecompressed(false),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tree := handler.tree.Load()
+
+	index, _ := fs.ReadFile(tree.mfilesys, "index.html")
+	if !strings.Contains(string(index), "<title>My App</title>") || !strings.Contains(string(index), "window.APP_ENV") {
+		t.Errorf("Expected placeholders to be replaced and web env to be injected, but got %q", index)
+	}
+
+	app, _ := fs.ReadFile(tree.mfilesys, "app.js")
+	if string(app) != `fetch("https://api.example.com");` {
+		t.Errorf("Expected placeholder to be replaced, but got %q", app)
+	}
+
+	// Assert that the sidecars holding the placeholder are not served
+	req := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Expected no Content-Encoding, but got %q", got)
+	}
+	if got := w.Body.String(); got != `fetch("https://api.example.com");` {
+		t.Errorf("Expected placeholder to be replaced, but got %q", got)
+	}
+
+	if !strings.Contains(logs.String(), "__SPASERVE_EXTRA__") {
+		t.Errorf("Expected unused placeholder to be logged, but got %q", logs.String())
+	}
+}
@@ -0,0 +1,105 @@
+package spaserve
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// contentEncoding maps a content coding to the extension of its precompressed sidecar file.
+type contentEncoding struct {
+	coding string
+	ext    string
+}
+
+// precompressedEncodings are the supported sidecar encodings in order of preference.
+var precompressedEncodings = []contentEncoding{
+	{coding: "br", ext: ".br"},
+	{coding: "gzip", ext: ".gz"},
+}
+
+// negotiateEncoding returns the preferred precompressed sidecar of name which is accepted by the client.
+// The returned bool reports whether any sidecar exists for name, which means the response varies by Accept-Encoding.
+func negotiateEncoding(filesys fs.FS, name string, acceptEncoding string) (contentEncoding, bool, bool) {
+	var hasSidecar bool
+	for _, enc := range precompressedEncodings {
+		if !fileExists(filesys, name+enc.ext) {
+			continue
+		}
+		hasSidecar = true
+		if acceptsEncoding(acceptEncoding, enc.coding) {
+			return enc, true, true
+		}
+	}
+	return contentEncoding{}, false, hasSidecar
+}
+
+// acceptsEncoding returns true if the Accept-Encoding header value allows the given content coding
+func acceptsEncoding(acceptEncoding string, coding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != coding && name != "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(k), "q") {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+
+		// an explicit entry for the coding always wins over the wildcard
+		if name == coding {
+			return q > 0
+		}
+		wildcard = q > 0
+	}
+	return wildcard
+}
+
+// isPrecompressedSidecar returns true if name is a sidecar of an existing file in the given file system
+func isPrecompressedSidecar(filesys fs.FS, name string) bool {
+	for _, enc := range precompressedEncodings {
+		if original, ok := strings.CutSuffix(name, enc.ext); ok && original != "" && fileExists(filesys, original) {
+			return true
+		}
+	}
+	return false
+}
+
+// servePrecompressed sets the encoding headers for the negotiated sidecar of name and returns the sidecar name.
+// It returns false if the original file should be served instead.
+func servePrecompressed(filesys fs.FS, w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	// the content type has to come from the original file as sniffing compressed data is not possible
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		return "", false
+	}
+
+	enc, ok, vary := negotiateEncoding(filesys, name, r.Header.Get("Accept-Encoding"))
+	if vary {
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+	if !ok {
+		return "", false
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Encoding", enc.coding)
+	return name + enc.ext, true
+}
+
+// fileExists returns true if name exists in the given file system and is not a directory
+func fileExists(filesys fs.FS, name string) bool {
+	info, err := fs.Stat(filesys, name)
+	return err == nil && !info.IsDir()
+}
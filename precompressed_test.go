@@ -0,0 +1,29 @@
+package spaserve
+
+import "testing"
+
+func TestAcceptsEncoding(t *testing.T) {
+	tt := []struct {
+		name           string
+		acceptEncoding string
+		coding         string
+		want           bool
+	}{
+		{name: "empty header", acceptEncoding: "", coding: "gzip", want: false},
+		{name: "listed coding", acceptEncoding: "gzip, deflate", coding: "gzip", want: true},
+		{name: "unlisted coding", acceptEncoding: "gzip, deflate", coding: "br", want: false},
+		{name: "case insensitive", acceptEncoding: "GZIP", coding: "gzip", want: true},
+		{name: "quality zero", acceptEncoding: "br;q=0, gzip", coding: "br", want: false},
+		{name: "quality non zero", acceptEncoding: "br;q=0.5", coding: "br", want: true},
+		{name: "wildcard", acceptEncoding: "*", coding: "br", want: true},
+		{name: "wildcard with explicit refusal", acceptEncoding: "*, br;q=0", coding: "br", want: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := acceptsEncoding(tc.acceptEncoding, tc.coding); got != tc.want {
+				t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", tc.acceptEncoding, tc.coding, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxRenderedVariants bounds the number of cached document variants per tree
+const maxRenderedVariants = 1024
+
+// renderedVariant is an entry document with the web env of a provider injected
+type renderedVariant struct {
+	data         []byte
+	scriptHashes []string
+}
+
+// variantCache caches rendered document variants by document and provider key
+type variantCache struct {
+	mu       sync.RWMutex
+	variants map[string]*renderedVariant
+}
+
+func newVariantCache() *variantCache {
+	return &variantCache{variants: map[string]*renderedVariant{}}
+}
+
+// get returns the cached variant for the key or renders and caches it
+func (c *variantCache) get(key string, render func() (*renderedVariant, error)) (*renderedVariant, error) {
+	c.mu.RLock()
+	v, ok := c.variants[key]
+	c.mu.RUnlock()
+	if ok {
+		return v, nil
+	}
+
+	v, err := render()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// start over instead of growing without bounds
+	if len(c.variants) >= maxRenderedVariants {
+		c.variants = map[string]*renderedVariant{}
+	}
+	c.variants[key] = v
+	return v, nil
+}
+
+// needsRendering returns true if entry documents have to be rendered for every request
+func (h *StaticFilesHandler) needsRendering() bool {
+	return h.opts.webEnvProvider != nil || (h.opts.csp != nil && h.opts.csp.mode == CSPModeNonce)
+}
+
+// serveRenderedDocument serves the entry document with the web env of the provider injected
+// and a fresh nonce stamped onto its inline scripts, depending on the options
+func (h *StaticFilesHandler) serveRenderedDocument(w http.ResponseWriter, r *http.Request, tree *staticFilesTree, name string) {
+	ctx := r.Context()
+
+	data, err := fs.ReadFile(tree.mfilesys, name)
+	if err != nil {
+		h.logger.logContext(ctx, slog.LevelError, "could not read document", slog.Attr{Key: "document", Value: slog.StringValue(name)}, slog.Attr{Key: "error", Value: slog.AnyValue(err)})
+		h.muxErrHandler(http.StatusInternalServerError, w, r)
+		return
+	}
+	scriptHashes := tree.scriptHashes[name]
+
+	// inject the web env of the provider
+	if h.opts.webEnvProvider != nil {
+		variant, err := h.renderVariant(r, tree, name, data)
+		if err != nil {
+			h.logger.logContext(ctx, slog.LevelError, "could not render web env", slog.Attr{Key: "document", Value: slog.StringValue(name)}, slog.Attr{Key: "error", Value: slog.AnyValue(err)})
+			h.muxErrHandler(http.StatusInternalServerError, w, r)
+			return
+		}
+		data, scriptHashes = variant.data, variant.scriptHashes
+	}
+
+	// set the content security policy
+	if h.opts.csp != nil {
+		sources := scriptHashes
+		if h.opts.csp.mode == CSPModeNonce {
+			nonce, err := newNonce()
+			if err == nil {
+				data, err = stampNonce(data, nonce)
+			}
+			if err != nil {
+				h.logger.logContext(ctx, slog.LevelError, "could not stamp nonce", slog.Attr{Key: "document", Value: slog.StringValue(name)}, slog.Attr{Key: "error", Value: slog.AnyValue(err)})
+				h.muxErrHandler(http.StatusInternalServerError, w, r)
+				return
+			}
+			sources = []string{"'nonce-" + nonce + "'"}
+		}
+		w.Header().Set("Content-Security-Policy", cspHeader(h.opts.csp.policy, sources))
+	}
+
+	// a nonce makes every response unique so it can not be validated
+	if h.opts.csp == nil || h.opts.csp.mode != CSPModeNonce {
+		w.Header().Set("ETag", contentETag(data))
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// renderVariant returns the document with the web env of the provider for the request injected. Variants are cached
+// by the key the provider returns, so the env is only checked and marshalled once per key.
+func (h *StaticFilesHandler) renderVariant(r *http.Request, tree *staticFilesTree, name string, data []byte) (*renderedVariant, error) {
+	key, env, err := h.opts.webEnvProvider(r)
+	if err != nil {
+		return nil, errors.Join(ErrCouldNotProvideWebEnv, err)
+	}
+
+	return tree.variants.get(name+"\x00"+key, func() (*renderedVariant, error) {
+		scriptTag, err := constructProvidedScriptTag(h.opts.webEnvProviderNs, env)
+		if err != nil {
+			return nil, err
+		}
+
+		rendered, err := appendToHead([]*html.Node{scriptTag}, data)
+		if err != nil {
+			return nil, err
+		}
+
+		variant := &renderedVariant{data: rendered}
+		if h.opts.csp != nil && h.opts.csp.mode == CSPModeHash {
+			if variant.scriptHashes, err = inlineScriptHashes(rendered); err != nil {
+				return nil, err
+			}
+		}
+		return variant, nil
+	})
+}
+
+// constructProvidedScriptTag checks the web env of a provider for secrets and constructs its script tag
+func constructProvidedScriptTag(ns string, env any) (*html.Node, error) {
+	if _, err := checkSecrets(env); err != nil {
+		return nil, errors.Join(ErrCouldNotProvideWebEnv, err)
+	}
+	return constructScriptTag(ns, env)
+}
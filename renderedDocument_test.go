@@ -0,0 +1,124 @@
+package spaserve
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestWebEnvProvider(t *testing.T) {
+	filesys := memfs.New()
+	_ = filesys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+
+	provider := func(r *http.Request) (string, any, error) {
+		if r.Host == "broken.example.com" {
+			return "", nil, errors.New("unknown tenant")
+		}
+		tenant := strings.Split(r.Host, ".")[0]
+		return tenant, map[string]string{"tenant": tenant}, nil
+	}
+
+	serve := func(handler http.Handler, host string, p string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, p, nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("renders env per request", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(filesys, WithWebEnvProvider(provider, "TENANT_ENV"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for _, host := range []string{"acme.example.com", "globex.example.com", "acme.example.com"} {
+			w := serve(handler, host, "/some/route")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, but got %d", http.StatusOK, w.Code)
+			}
+
+			want := `window.TENANT_ENV = {"tenant":"` + strings.Split(host, ".")[0] + `"};`
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("Expected body to contain %q, but got %q", want, w.Body.String())
+			}
+
+			if w.Header().Get("ETag") == "" {
+				t.Error("Expected ETag to be set")
+			}
+		}
+
+		// Assert that variants are cached by the key of the provider
+		tree := handler.tree.Load()
+		if got := len(tree.variants.variants); got != 2 {
+			t.Errorf("Expected 2 cached variants, but got %d", got)
+		}
+	})
+
+	t.Run("skips marshalling on cache hits", func(t *testing.T) {
+		var marshalled int
+		handler, err := NewStaticFilesHandler(filesys, WithWebEnvProvider(func(r *http.Request) (string, any, error) {
+			return "acme", countingEnv{count: &marshalled}, nil
+		}, ""))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			if w := serve(handler, "acme.example.com", "/"); w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, but got %d", http.StatusOK, w.Code)
+			}
+		}
+
+		if marshalled != 1 {
+			t.Errorf("Expected the env to be marshalled once, but got %d", marshalled)
+		}
+	})
+
+	t.Run("returns 500 on provider error", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(filesys, WithWebEnvProvider(provider, ""))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if w := serve(handler, "broken.example.com", "/"); w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status code %d, but got %d", http.StatusInternalServerError, w.Code)
+		}
+	})
+
+	t.Run("hashes rendered env for content security policy", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(filesys, WithWebEnvProvider(provider, ""), WithContentSecurityPolicy(CSPModeHash, ""))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		sum := sha256.Sum256([]byte(`window.APP_ENV = {"tenant":"acme"};`))
+		want := "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+
+		if got := serve(handler, "acme.example.com", "/").Header().Get("Content-Security-Policy"); !strings.Contains(got, want) {
+			t.Errorf("Expected Content-Security-Policy to contain %q, but got %q", want, got)
+		}
+	})
+
+	t.Run("rejects invalid namespace", func(t *testing.T) {
+		if _, err := NewStaticFilesHandler(filesys, WithWebEnvProvider(provider, "!!!")); !errors.Is(err, ErrCouldNotParseNamespace) {
+			t.Errorf("Expected ErrCouldNotParseNamespace, but got %v", err)
+		}
+	})
+}
+
+// countingEnv counts how often it is marshalled
+type countingEnv struct {
+	count *int
+}
+
+func (e countingEnv) MarshalJSON() ([]byte, error) {
+	*e.count++
+	return []byte(`{"tenant":"acme"}`), nil
+}
@@ -0,0 +1,188 @@
+package spaserve
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// secretTag is the struct tag value marking a field as secret (i.e. `spaserve:"secret"`)
+const secretTag = "secret"
+
+// secretNameHints are the parts of field names which are likely secrets
+var secretNameHints = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "privatekey", "private_key", "credential"}
+
+// Secret marks a value which must never be injected into the web environment. A field of this type makes the
+// injection fail with a SecretFieldError, and marshalling it to JSON always fails.
+type Secret string
+
+// MarshalJSON refuses to marshal the secret
+func (Secret) MarshalJSON() ([]byte, error) {
+	return nil, ErrSecretField
+}
+
+// SecretFieldError is returned when the web environment contains a field tagged with `spaserve:"secret"`
+// or of type Secret.
+type SecretFieldError struct {
+	// Field is the path of the field (e.g. Database.Password)
+	Field string
+}
+
+func (e *SecretFieldError) Error() string {
+	return ErrSecretField.Error() + ": " + e.Field
+}
+
+// Unwrap allows errors.Is(err, ErrSecretField)
+func (e *SecretFieldError) Unwrap() error {
+	return ErrSecretField
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// checkSecrets returns an error for the first secret field of the config and the paths of fields whose name
+// looks like a secret
+func checkSecrets(conf any) ([]string, error) {
+	var suspicious []string
+	err := walkSecrets(reflect.ValueOf(conf), "", &suspicious, map[visitKey]bool{})
+	sort.Strings(suspicious)
+	return suspicious, err
+}
+
+// visitKey identifies a pointer, map or slice on the path of walkSecrets
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// walkSecrets recursively walks the value, visiting guards against cycles of pointers, maps and slices which
+// json can not marshal either
+func walkSecrets(v reflect.Value, path string, suspicious *[]string, visiting map[visitKey]bool) error {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type() == secretType {
+		return &SecretFieldError{Field: path}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		key := visitKey{ptr: v.Pointer(), typ: v.Type()}
+		if v.Kind() == reflect.Slice {
+			key.len = v.Len()
+		}
+		if visiting[key] {
+			if path == "" {
+				path = "."
+			}
+			return errors.Join(ErrCouldNotMarshalConfig, errors.New("cycle at "+path))
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		return walkSecrets(v.Elem(), path, suspicious, visiting)
+	case reflect.Interface:
+		return walkSecrets(v.Elem(), path, suspicious, visiting)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if (!field.IsExported() && !field.Anonymous) || field.Tag.Get("json") == "-" {
+				continue
+			}
+
+			fieldPath := joinFieldPath(path, field.Name)
+			if field.Anonymous {
+				// embedded fields are marshalled into the parent
+				fieldPath = path
+			}
+			if hasSecretTag(field) {
+				return &SecretFieldError{Field: joinFieldPath(path, field.Name)}
+			}
+			if !field.Anonymous && looksLikeSecret(field.Name) {
+				*suspicious = append(*suspicious, fieldPath)
+			}
+			if err := walkSecrets(v.Field(i), fieldPath, suspicious, visiting); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			name := ""
+			if key.Kind() == reflect.String {
+				name = key.String()
+				if looksLikeSecret(name) {
+					*suspicious = append(*suspicious, joinFieldPath(path, name))
+				}
+			}
+			if err := walkSecrets(iter.Value(), joinFieldPath(path, name), suspicious, visiting); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkSecrets(v.Index(i), path+"["+strconv.Itoa(i)+"]", suspicious, visiting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasSecretTag returns true if the field is tagged with `spaserve:"secret"`
+func hasSecretTag(field reflect.StructField) bool {
+	for _, value := range strings.Split(field.Tag.Get("spaserve"), ",") {
+		if strings.TrimSpace(value) == secretTag {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeSecret returns true if the name contains one of the secret name hints
+func looksLikeSecret(name string) bool {
+	name = strings.ToLower(name)
+	for _, hint := range secretNameHints {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinFieldPath joins the field name to the path with a dot
+func joinFieldPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// validateSecrets returns an error if the config has a secret field and logs a warning for every field which
+// looks like a secret
+func validateSecrets(logger *servespaLogger, ns string, conf any) error {
+	suspicious, err := checkSecrets(conf)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range suspicious {
+		logger.logContext(context.Background(), slog.LevelWarn, "web env field looks like a secret and will be public",
+			slog.Attr{Key: "namespace", Value: slog.StringValue(ns)},
+			slog.Attr{Key: "field", Value: slog.StringValue(field)},
+		)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/psanford/memfs"
+)
+
+func TestCheckSecrets(t *testing.T) {
+	type database struct {
+		Host     string `json:"host"`
+		Password string `json:"password" spaserve:"secret"`
+	}
+	type auth struct {
+		ClientID    string `json:"clientId"`
+		AccessToken string `json:"accessToken"`
+	}
+
+	tt := []struct {
+		name           string
+		conf           any
+		wantField      string
+		wantSuspicious []string
+	}{
+		{
+			name: "no secrets",
+			conf: struct {
+				API string `json:"api"`
+			}{API: "/api"},
+		},
+		{
+			name: "tagged field",
+			conf: struct {
+				DB database `json:"db"`
+			}{},
+			wantField: "DB.Password",
+		},
+		{
+			name: "tagged field behind a pointer",
+			conf: &struct {
+				DB *database `json:"db"`
+			}{DB: &database{}},
+			wantField: "DB.Password",
+		},
+		{
+			name:      "secret type",
+			conf:      map[string]any{"db": map[string]any{"dsn": Secret("postgres://")}},
+			wantField: "db.dsn",
+		},
+		{
+			name: "ignored fields are not checked",
+			conf: struct {
+				DB database `json:"-"`
+			}{},
+		},
+		{
+			name: "fields which look like secrets",
+			conf: struct {
+				Auth   auth              `json:"auth"`
+				Extra  map[string]string `json:"extra"`
+				Tokens []string          `json:"tokens"`
+			}{Extra: map[string]string{"stripeSecret": "x", "name": "y"}},
+			wantSuspicious: []string{"Auth.AccessToken", "Extra.stripeSecret", "Tokens"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			suspicious, err := checkSecrets(tc.conf)
+
+			var secretErr *SecretFieldError
+			if tc.wantField != "" {
+				if !errors.As(err, &secretErr) || secretErr.Field != tc.wantField {
+					t.Fatalf("checkSecrets() error = %v, want secret field %s", err, tc.wantField)
+				}
+				if !errors.Is(err, ErrSecretField) {
+					t.Errorf("checkSecrets() error = %v, want %v", err, ErrSecretField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkSecrets() returned an unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(suspicious, tc.wantSuspicious) {
+				t.Errorf("checkSecrets() = %v, want %v", suspicious, tc.wantSuspicious)
+			}
+		})
+	}
+}
+
+func TestCheckSecretsCycles(t *testing.T) {
+	type node struct {
+		Next *node `json:"next"`
+	}
+
+	cyclicMap := map[string]any{}
+	cyclicMap["self"] = cyclicMap
+
+	cyclicSlice := []any{nil}
+	cyclicSlice[0] = cyclicSlice
+
+	cyclicPointer := &node{}
+	cyclicPointer.Next = cyclicPointer
+
+	for name, conf := range map[string]any{
+		"map":     cyclicMap,
+		"slice":   cyclicSlice,
+		"pointer": cyclicPointer,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := checkSecrets(conf); !errors.Is(err, ErrCouldNotMarshalConfig) {
+				t.Errorf("checkSecrets() error = %v, want %v", err, ErrCouldNotMarshalConfig)
+			}
+		})
+	}
+
+	t.Run("shared values are not cycles", func(t *testing.T) {
+		shared := map[string]string{"a": "b"}
+		if _, err := checkSecrets(map[string]any{"x": shared, "y": shared}); err != nil {
+			t.Errorf("checkSecrets() returned an unexpected error: %v", err)
+		}
+	})
+}
+
+func TestSecretMarshalJSON(t *testing.T) {
+	if _, err := Secret("x").MarshalJSON(); !errors.Is(err, ErrSecretField) {
+		t.Errorf("MarshalJSON() error = %v, want %v", err, ErrSecretField)
+	}
+}
+
+func TestInjectWebEnvSecrets(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+
+	conf := struct {
+		API        string `json:"api"`
+		DBPassword string `json:"dbPassword" spaserve:"secret"`
+	}{API: "/api", DBPassword: "hunter2"}
+
+	_, err := InjectWebEnv(fsys, conf, "APP_ENV")
+	var secretErr *SecretFieldError
+	if !errors.As(err, &secretErr) || secretErr.Field != "DBPassword" {
+		t.Errorf("InjectWebEnv() error = %v, want secret field DBPassword", err)
+	}
+
+	t.Run("handler refuses secrets of every web env source", func(t *testing.T) {
+		for name, fn := range map[string]staticFilesHandlerFunc{
+			"inject":       WithInjectWebEnv(conf, ""),
+			"endpoint":     WithWebEnvEndpoint("env.js", WebEnvFormatScript, conf, ""),
+			"placeholders": WithPlaceholders(conf, ""),
+		} {
+			if _, err := NewStaticFilesHandler(fsys, fn); !errors.Is(err, ErrSecretField) {
+				t.Errorf("%s: NewStaticFilesHandler() error = %v, want %v", name, err, ErrSecretField)
+			}
+		}
+	})
+
+	t.Run("warns about fields which look like secrets", func(t *testing.T) {
+		logs := new(bytes.Buffer)
+		logger := slog.New(slog.NewJSONHandler(logs, nil))
+
+		_, err := NewStaticFilesHandler(fsys, WithLogger(logger), WithInjectWebEnv(map[string]string{"apiToken": "x"}, ""))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(logs.String(), `"field":"apiToken"`) {
+			t.Errorf("Expected a warning for apiToken, but got %q", logs.String())
+		}
+
+		logs.Reset()
+		_, err = InjectWebEnv(fsys, map[string]string{"apiToken": "x"}, "APP_ENV", WithInjectLogger(logger))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(logs.String(), `"field":"apiToken"`) {
+			t.Errorf("Expected a warning for apiToken, but got %q", logs.String())
+		}
+	})
+
+	t.Run("provider refuses secrets", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(fsys, WithWebEnvProvider(func(*http.Request) (string, any, error) { return "", conf, nil }, ""))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := handler.renderVariant(nil, handler.tree.Load(), "index.html", []byte("<html></html>")); !errors.Is(err, ErrSecretField) {
+			t.Errorf("renderVariant() error = %v, want %v", err, ErrSecretField)
+		}
+	})
+}
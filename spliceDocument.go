@@ -0,0 +1,121 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// voidElements are the html elements which have no end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// spliceAt inserts the rendered nodes at the position of the given html document without touching any other byte.
+// The tokenizer is only used to locate the byte range to replace, so the anchor has to be written explicitly
+// in the document (e.g. an implied <head> can not be found) and elements injected after have to be closed explicitly.
+func spliceAt(nodes []*html.Node, d []byte, pos InjectPosition) ([]byte, error) {
+	if pos.err != nil {
+		return nil, pos.err
+	}
+
+	start, end, err := findSpliceRange(d, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Grow(len(d))
+	b.Write(d[:start])
+	for _, n := range nodes {
+		if err := html.Render(&b, cloneNode(n)); err != nil {
+			return nil, errors.Join(ErrCouldNotWriteIndex, err)
+		}
+	}
+	b.Write(d[end:])
+	return b.Bytes(), nil
+}
+
+// findSpliceRange returns the byte range of the document which is replaced by the injected nodes.
+// The range is empty unless a marker comment is replaced.
+func findSpliceRange(d []byte, pos InjectPosition) (int, int, error) {
+	z := html.NewTokenizer(bytes.NewReader(d))
+
+	var (
+		offset int
+		// anchor is the tag name and depth of the element matched by InjectAfter until its end tag is found
+		anchor string
+		depth  int
+	)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if !errors.Is(z.Err(), io.EOF) {
+				return 0, 0, errors.Join(ErrCouldNotParseIndex, z.Err())
+			}
+			if anchor != "" {
+				// the anchor is never closed so it extends to the end of the document
+				return len(d), len(d), nil
+			}
+			break
+		}
+
+		start := offset
+		offset += len(z.Raw())
+		tok := z.Token()
+
+		if anchor != "" {
+			switch {
+			case tt == html.StartTagToken && tok.Data == anchor:
+				depth++
+			case tt == html.EndTagToken && tok.Data == anchor:
+				if depth--; depth == 0 {
+					return offset, offset, nil
+				}
+			}
+			continue
+		}
+
+		switch pos.kind {
+		case injectHeadStart:
+			if tt == html.StartTagToken && tok.Data == "head" {
+				return offset, offset, nil
+			}
+		case injectHeadEnd:
+			if tt == html.EndTagToken && tok.Data == "head" {
+				return start, start, nil
+			}
+		case injectBodyEnd:
+			if tt == html.EndTagToken && tok.Data == "body" {
+				return start, start, nil
+			}
+		case injectMarker:
+			if tt == html.CommentToken && strings.TrimSpace(tok.Data) == pos.marker {
+				return start, offset, nil
+			}
+		case injectBefore, injectAfter:
+			if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+				continue
+			}
+			if !pos.selector.matches(&html.Node{Type: html.ElementNode, Data: tok.Data, Attr: tok.Attr}) {
+				continue
+			}
+			if pos.kind == injectBefore {
+				return start, start, nil
+			}
+			if tt == html.SelfClosingTagToken || voidElements[tok.Data] {
+				return offset, offset, nil
+			}
+			anchor, depth = tok.Data, 1
+		}
+	}
+
+	if pos.kind == injectHeadStart || pos.kind == injectHeadEnd {
+		return 0, 0, ErrCouldNotFindHead
+	}
+	return 0, 0, errors.Join(ErrCouldNotFindAnchor, errors.New(pos.String()))
+}
@@ -0,0 +1,108 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestSpliceAt(t *testing.T) {
+	original, err := os.ReadFile(path.Join("testdata", "splice", "index.html"))
+	if err != nil {
+		t.Fatalf("could not read testdata: %v", err)
+	}
+
+	nodes, err := constructEnvNodes("APP_ENV", map[string]string{"api": "https://api.example.com"}, InjectFormatScript)
+	if err != nil {
+		t.Fatalf("constructEnvNodes() returned an unexpected error: %v", err)
+	}
+	var injected bytes.Buffer
+	_ = html.Render(&injected, nodes[0])
+
+	tt := []struct {
+		name     string
+		position InjectPosition
+		golden   string
+	}{
+		{name: "head start", position: InjectAtHeadStart(), golden: "head-start.html"},
+		{name: "head end", position: InjectAtHeadEnd(), golden: "head-end.html"},
+		{name: "after import map", position: InjectAfter("script[type=importmap]"), golden: "after-importmap.html"},
+		{name: "after void element", position: InjectAfter("meta[charset]"), golden: "after-meta.html"},
+		{name: "before link", position: InjectBefore("link"), golden: "before-link.html"},
+		{name: "body end", position: InjectAtBodyEnd(), golden: "body-end.html"},
+		{name: "marker", position: InjectAtMarker(""), golden: "marker.html"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := spliceAt(nodes, original, tc.position)
+			if err != nil {
+				t.Fatalf("spliceAt() returned an unexpected error: %v", err)
+			}
+
+			want, err := os.ReadFile(path.Join("testdata", "splice", tc.golden))
+			if err != nil {
+				t.Fatalf("could not read golden file: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("spliceAt() = %s, want %s", got, want)
+			}
+
+			// removing the injected bytes has to restore the original document, except for the replaced marker
+			restored := bytes.Replace(got, injected.Bytes(), nil, 1)
+			if tc.position.kind == injectMarker {
+				restored = bytes.Replace(original, []byte("<!-- spaserve:env -->"), nil, 1)
+				if !bytes.Equal(bytes.Replace(got, injected.Bytes(), nil, 1), restored) {
+					t.Errorf("spliceAt() changed bytes other than the marker")
+				}
+				return
+			}
+			if !bytes.Equal(restored, original) {
+				t.Errorf("spliceAt() changed bytes other than the injected nodes")
+			}
+		})
+	}
+}
+
+func TestSpliceAtMissingAnchor(t *testing.T) {
+	tt := []struct {
+		name     string
+		data     string
+		position InjectPosition
+		wantErr  error
+	}{
+		{name: "implied head", data: "<title>app</title>", position: InjectAtHeadStart(), wantErr: ErrCouldNotFindHead},
+		{name: "missing body end", data: "<html><body>", position: InjectAtBodyEnd(), wantErr: ErrCouldNotFindAnchor},
+		{name: "missing selector", data: "<html><head></head></html>", position: InjectBefore("script"), wantErr: ErrCouldNotFindAnchor},
+		{name: "missing marker", data: "<!-- other -->", position: InjectAtMarker(""), wantErr: ErrCouldNotFindAnchor},
+		{name: "invalid selector", data: "<html></html>", position: InjectAfter("a b"), wantErr: ErrCouldNotParseSelector},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := spliceAt(nil, []byte(tc.data), tc.position); !errors.Is(err, tc.wantErr) {
+				t.Errorf("spliceAt() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestInjectWebEnvWithPreserveBytes(t *testing.T) {
+	filesys := os.DirFS(path.Join("testdata", "splice"))
+
+	result, err := InjectWebEnv(filesys, map[string]string{"api": "https://api.example.com"}, "APP_ENV", WithPreserveBytes(), WithInjectPosition(InjectAtMarker("")))
+	if err != nil {
+		t.Fatalf("InjectWebEnv() returned an unexpected error: %v", err)
+	}
+
+	got, _ := fs.ReadFile(result, "index.html")
+	want, _ := os.ReadFile(path.Join("testdata", "splice", "marker.html"))
+	if !bytes.Equal(got, want) {
+		t.Errorf("InjectWebEnv() = %s, want %s", got, want)
+	}
+}
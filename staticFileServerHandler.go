@@ -1,41 +1,92 @@
 package spaserve
 
 import (
+	"context"
 	"errors"
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"net/http/httputil"
 	"os"
 	"path"
+	"slices"
 	"strings"
-
-	"github.com/psanford/memfs"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type StaticFilesHandler struct {
 	opts          staticFilesHandlerOpts
-	fileServer    http.Handler
-	mfilesys      *memfs.FS
+	tree          atomic.Pointer[staticFilesTree]
+	reloadMu      sync.Mutex
+	proxy         *httputil.ReverseProxy
+	endpoints     map[string]renderedWebEnvEndpoint
 	logger        *servespaLogger
 	muxErrHandler func(int, http.ResponseWriter, *http.Request)
 }
 
 type staticFilesHandlerOpts struct {
-	ns            string
-	basePath      string
-	logger        *slog.Logger
-	muxErrHandler func(int) http.Handler
-	webEnv        any
+	basePath          string
+	logger            *slog.Logger
+	muxErrHandler     func(int) http.Handler
+	webEnvs           []webEnvInjection
+	precompressed     bool
+	hideSidecars      bool
+	compress          bool
+	cacheControl      *CacheControlPolicy
+	etags             bool
+	watchCtx          context.Context
+	watchInterval     time.Duration
+	devServer         string
+	entryDocument     string
+	fallbacks         []fallbackRule
+	csp               *cspOpts
+	webEnvProvider    func(*http.Request) (string, any, error)
+	webEnvProviderNs  string
+	webEnvEndpoints   []webEnvEndpoint
+	placeholders      any
+	placeholderPrefix string
+	envKeyAnalysis    bool
+	envKeyStrict      bool
+	directoryPolicy   DirectoryPolicy
+}
+
+// fallbackRule serves the document for undefined routes matching the pattern
+type fallbackRule struct {
+	pattern  string
+	document string
 }
 
 type staticFilesHandlerFunc func(staticFilesHandlerOpts) staticFilesHandlerOpts
 
+// defaultNamespace is the namespace of web environments if none is provided
+const defaultNamespace = "APP_ENV"
+
 var defaultStaticFilesHandlerOpts = staticFilesHandlerOpts{
-	ns:            "APP_ENV",
-	basePath:      "/",
-	logger:        nil,
-	muxErrHandler: nil,
-	webEnv:        nil,
+	basePath:          "/",
+	logger:            nil,
+	muxErrHandler:     nil,
+	webEnvs:           nil,
+	precompressed:     false,
+	hideSidecars:      false,
+	compress:          false,
+	cacheControl:      nil,
+	etags:             false,
+	watchCtx:          nil,
+	watchInterval:     0,
+	devServer:         "",
+	entryDocument:     "index.html",
+	fallbacks:         nil,
+	csp:               nil,
+	webEnvProvider:    nil,
+	webEnvProviderNs:  defaultNamespace,
+	webEnvEndpoints:   nil,
+	placeholders:      nil,
+	placeholderPrefix: DefaultPlaceholderPrefix,
+	envKeyAnalysis:    false,
+	envKeyStrict:      false,
+	directoryPolicy:   DirectoryPolicyFallback,
 }
 
 // WithLogger sets the logger for the static file server. Defaults to slog.Logger.
@@ -48,6 +99,16 @@ func WithLogger(logger *slog.Logger) staticFilesHandlerFunc {
 
 // WithBasePath sets the base path for the web server which will be trimmed from the request path before looking up files.
 func WithBasePath(basePath string) staticFilesHandlerFunc {
+	basePath = normalizeBasePath(basePath)
+
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.basePath = basePath
+		return c
+	}
+}
+
+// normalizeBasePath returns the base path with a leading and trailing slash, empty base paths default to "/"
+func normalizeBasePath(basePath string) string {
 	if basePath == "" {
 		basePath = defaultStaticFilesHandlerOpts.basePath
 	}
@@ -62,10 +123,7 @@ func WithBasePath(basePath string) staticFilesHandlerFunc {
 		basePath = basePath + "/"
 	}
 
-	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
-		c.basePath = basePath
-		return c
-	}
+	return basePath
 }
 
 // WithMuxErrorHandler sets custom error handlers for the static file server.
@@ -78,63 +136,341 @@ func WithMuxErrorHandler(handler func(int) http.Handler) staticFilesHandlerFunc
 	}
 }
 
-// WithInjectWebEnv injects the web environment into the static file server.
+// WithInjectWebEnv injects the web environment into the static file server. It is injected into the entry and
+// fallback documents unless the documents are selected with WithInjectDocuments or WithInjectDocumentFunc.
+// It can be used multiple times to inject several namespaces (e.g. APP_ENV, FEATURE_FLAGS and BUILD_INFO), which are
+// injected in the order of the options. Namespaces have to be unique.
 //
-//	env: the web environment to inject, use json struct tags to drive the marshalling
+//	env: the web environment to inject, use json struct tags to drive the marshalling, a nil env is not injected
 //	namespace: the namespace to use for the web environment, defaults to "APP_ENV"
-func WithInjectWebEnv(env any, namespace string) staticFilesHandlerFunc {
+//	fn: optional functions to configure the injection (e.g. WithInjectFormat)
+func WithInjectWebEnv(env any, namespace string, fn ...injectWebEnvFunc) staticFilesHandlerFunc {
 	if namespace == "" {
-		namespace = defaultStaticFilesHandlerOpts.ns
+		namespace = defaultNamespace
+	}
+
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		if env == nil {
+			return c
+		}
+		c.webEnvs = append(c.webEnvs[:len(c.webEnvs):len(c.webEnvs)], webEnvInjection{env: env, ns: namespace, fn: fn})
+		return c
+	}
+}
+
+// WithPrecompressed serves precompressed sidecar files (e.g. app.js.br, app.js.gz) to clients that accept the encoding.
+//
+//	hideSidecars: respond with 404 when a sidecar is requested directly
+func WithPrecompressed(hideSidecars bool) staticFilesHandlerFunc {
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.precompressed = true
+		c.hideSidecars = hideSidecars
+		return c
+	}
+}
+
+// WithCompression gzip compresses compressible files (js, css, html, svg, json, wasm) once when the handler is created
+// and serves the compressed files to clients that accept gzip. Existing sidecars are served as with WithPrecompressed.
+func WithCompression() staticFilesHandlerFunc {
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.compress = true
+		c.precompressed = true
+		return c
+	}
+}
+
+// WithCacheControl sets the Cache-Control header of served files according to the given policy.
+// Use DefaultCacheControlPolicy as a starting point.
+func WithCacheControl(policy CacheControlPolicy) staticFilesHandlerFunc {
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.cacheControl = &policy
+		return c
 	}
+}
+
+// WithETags computes a strong content hash ETag for every file when the handler is created and answers matching
+// If-None-Match requests with 304 Not Modified.
+func WithETags() staticFilesHandlerFunc {
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.etags = true
+		return c
+	}
+}
+
+// WithWatch polls the source file system for changes and rebuilds the served files, which is intended for development
+// (e.g. with vite build --watch). Rebuild errors are logged and the previous files stay in service.
+//
+//	ctx: the watcher stops when the context is done
+//	interval: the poll interval, changes are rebuilt once the file system is unchanged for one interval
+func WithWatch(ctx context.Context, interval time.Duration) staticFilesHandlerFunc {
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.watchCtx = ctx
+		c.watchInterval = interval
+		return c
+	}
+}
 
+// WithDevServerProxy proxies all requests, including HMR websockets, to a running dev server (e.g. http://localhost:5173)
+// instead of serving files from the file system, which may be nil. The base path is trimmed before proxying, navigation
+// requests fall back to the entry or fallback document and the web env is injected into proxied html documents.
+func WithDevServerProxy(upstream string) staticFilesHandlerFunc {
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.devServer = upstream
+		return c
+	}
+}
+
+// WithEntryDocument sets the document served for the root path and undefined routes. Defaults to "index.html".
+func WithEntryDocument(document string) staticFilesHandlerFunc {
+	if document == "" {
+		document = defaultStaticFilesHandlerOpts.entryDocument
+	}
+	document = strings.TrimPrefix(document, "/")
+
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.entryDocument = document
+		return c
+	}
+}
+
+// WithFallback serves the document for undefined routes matching the pattern instead of the entry document.
+// Rules are checked in the order they are added and the web env is injected into every fallback document.
+//
+//	pattern: a glob matched against the request path without base path, "**" matches any number of segments (e.g. "/admin/**")
+//	document: the path of the document to serve (e.g. "admin/index.html")
+func WithFallback(pattern string, document string) staticFilesHandlerFunc {
+	document = strings.TrimPrefix(document, "/")
+
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.fallbacks = append(c.fallbacks[:len(c.fallbacks):len(c.fallbacks)], fallbackRule{pattern: pattern, document: document})
+		return c
+	}
+}
+
+// WithWebEnvProvider injects the web environment returned by the provider for each request into the entry documents,
+// e.g. to render the env for the host, headers or auth context of the request. Rendered documents are cached by the
+// key the provider returns, so the env is only marshalled and the html only parsed once per key. When proxying to a
+// dev server the env is injected into proxied html documents without caching.
+//
+//	provider: returns the cache key and the web environment for the request, requests with the same key must have the
+//	same env, use json struct tags to drive the marshalling
+//	namespace: the namespace to use for the web environment, defaults to "APP_ENV"
+func WithWebEnvProvider(provider func(*http.Request) (key string, env any, err error), namespace string) staticFilesHandlerFunc {
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.webEnvProvider = provider
+		c.webEnvProviderNs = namespace
+		return c
+	}
+}
+
+// WithWebEnvEndpoint serves the web environment at the given path instead of injecting it into the html,
+// with a matching content type and no-cache headers. It can be used multiple times to serve several formats.
+//
+//	path: the path relative to the base path (e.g. "env.js")
+//	format: WebEnvFormatScript, WebEnvFormatModule or WebEnvFormatJSON
+//	env: the web environment to serve, use json struct tags to drive the marshalling
+//	namespace: the namespace to assign the web environment to in WebEnvFormatScript, defaults to "APP_ENV"
+func WithWebEnvEndpoint(path string, format WebEnvFormat, env any, namespace string) staticFilesHandlerFunc {
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	path = normalizeEndpointPath(path)
+
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.webEnvEndpoints = append(c.webEnvEndpoints[:len(c.webEnvEndpoints):len(c.webEnvEndpoints)], webEnvEndpoint{
+			path:   path,
+			format: format,
+			env:    env,
+			ns:     namespace,
+		})
+		return c
+	}
+}
+
+// WithPlaceholders replaces placeholders (e.g. __SPASERVE_API_URL__) in js, json, css and html files with the top-level
+// values of the config, for libraries which read config at module evaluation time. Placeholders without a value and
+// values without a placeholder are logged as warnings. See PlaceholderReplacer for the placeholder names and escaping.
+//
+//	conf: the config providing the values, use json struct tags to drive the marshalling
+//	prefix: the prefix of the placeholders, defaults to "__SPASERVE_"
+func WithPlaceholders(conf any, prefix string) staticFilesHandlerFunc {
+	if prefix == "" {
+		prefix = DefaultPlaceholderPrefix
+	}
+
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.placeholders = conf
+		c.placeholderPrefix = prefix
+		return c
+	}
+}
+
+// WithEnvKeyAnalysis scans the js bundles for references of the injected web env keys (e.g. window.APP_ENV.apiUrl)
+// when the files are built. Keys which are referenced but missing from the web env and keys which are not referenced
+// are logged as warnings and returned by EnvKeyReports.
+//
+//	strict: fail to build the files if a referenced key is missing
+func WithEnvKeyAnalysis(strict bool) staticFilesHandlerFunc {
 	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
-		c.webEnv = env
-		c.ns = namespace
+		c.envKeyAnalysis = true
+		c.envKeyStrict = strict
 		return c
 	}
 }
 
+// WithContentSecurityPolicy sets the Content-Security-Policy header of entry documents and allows their inline scripts,
+// including the injected web env, by adding their hashes or a per-request nonce to the script-src directive.
+//
+//	mode: CSPModeHash to hash the inline scripts once, CSPModeNonce to stamp a fresh nonce onto them for every response
+//	policy: the policy to extend, defaults to "script-src 'self'"
+func WithContentSecurityPolicy(mode CSPMode, policy string) staticFilesHandlerFunc {
+	if policy == "" {
+		policy = defaultCSPPolicy
+	}
+
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.csp = &cspOpts{mode: mode, policy: policy}
+		return c
+	}
+}
+
+// entryDocuments returns the entry document followed by the distinct fallback documents
+func (c staticFilesHandlerOpts) entryDocuments() []string {
+	documents := []string{c.entryDocument}
+	for _, rule := range c.fallbacks {
+		if !slices.Contains(documents, rule.document) {
+			documents = append(documents, rule.document)
+		}
+	}
+	return documents
+}
+
 // StaticFilesHandler creates a static file server handler that serves files from the given fs.FS.
 // It serves index.html for the root path and 404 for actual static file requests that don't exist.
 //   - ctx: the context
 //   - filesys: the file system to serve files from - this will be copied to a memfs
-//   - fn: optional functions to configure the handler (e.g. WithLogger, WithBasePath, WithMuxErrorHandler, WithInjectWebEnv)
-func NewStaticFilesHandler(filesys fs.FS, fn ...staticFilesHandlerFunc) (http.Handler, error) {
+//   - fn: optional functions to configure the handler (e.g. WithLogger, WithBasePath, WithMuxErrorHandler, WithInjectWebEnv, WithPrecompressed, WithCompression, WithCacheControl, WithETags, WithWatch)
+func NewStaticFilesHandler(filesys fs.FS, fn ...staticFilesHandlerFunc) (*StaticFilesHandler, error) {
 	// process options
 	opts := defaultStaticFilesHandlerOpts
 	for _, f := range fn {
 		opts = f(opts)
 	}
 
-	var (
-		mfilesys *memfs.FS
-		err      error
-	)
-	// inject web env if provided
-	if opts.webEnv != nil {
-		mfilesys, err = InjectWebEnv(filesys, opts.webEnv, opts.ns)
-	} else {
-		mfilesys, err = CopyFileSys(filesys, nil)
+	// validate the provider namespace as it is only used per request
+	if opts.webEnvProvider != nil {
+		ns, err := validateNamespace(opts.webEnvProviderNs)
+		if err != nil {
+			return nil, err
+		}
+		opts.webEnvProviderNs = ns
+
+		// the provided web env is injected next to the static ones, so the namespaces must not collide
+		for _, injection := range opts.webEnvs {
+			if namespacesCollide(strings.TrimSpace(injection.ns), ns) {
+				return nil, errors.Join(ErrDuplicateNamespace, errors.New(ns))
+			}
+		}
 	}
+
+	// render the web env endpoints once
+	endpoints, err := renderWebEnvEndpoints(opts.webEnvEndpoints, newLogger(opts.logger))
 	if err != nil {
 		return nil, err
 	}
 
-	// create file server
-	fileServer := http.FileServer(http.FS(mfilesys))
-	logger := newLogger(opts.logger)
-
-	return &StaticFilesHandler{
+	h := &StaticFilesHandler{
 		opts:          opts,
-		mfilesys:      mfilesys,
-		fileServer:    fileServer,
-		logger:        logger,
+		endpoints:     endpoints,
+		logger:        newLogger(opts.logger),
 		muxErrHandler: newMuxErrorHandler(opts.muxErrHandler),
-	}, nil
+	}
+
+	// proxy to the dev server instead of serving files
+	if opts.devServer != "" {
+		proxy, err := newDevServerProxy(opts.devServer, opts, h.logger, h.muxErrHandler)
+		if err != nil {
+			return nil, err
+		}
+		h.proxy = proxy
+		return h, nil
+	}
+
+	// snapshot before building so changes made during the build are picked up by the watcher
+	var snapshot uint64
+	if opts.watchInterval > 0 {
+		var err error
+		if snapshot, err = snapshotFileSys(filesys); err != nil {
+			return nil, err
+		}
+	}
+
+	tree, err := buildStaticFilesTree(filesys, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	h.tree.Store(tree)
+
+	// watch the source file system and rebuild on change
+	if opts.watchInterval > 0 {
+		go h.watch(opts.watchCtx, filesys, opts.watchInterval, snapshot)
+	}
+
+	return h, nil
+}
+
+// Reload builds the given file system with the options of the handler and atomically swaps it in.
+// In-flight requests finish on the previous files. If the build fails, the previous files stay in service
+// and the error is returned. Reloads are serialized, so the files of the last call to return are in service.
+// With WithWatch the watcher reloads the same way, it rebuilds the watched file system on its next change and
+// replaces files reloaded from another file system.
+func (h *StaticFilesHandler) Reload(filesys fs.FS) error {
+	if h.proxy != nil {
+		return ErrReloadDevServerProxy
+	}
+
+	// build and store under the lock so an older build never replaces a newer one
+	h.reloadMu.Lock()
+	defer h.reloadMu.Unlock()
+
+	tree, err := buildStaticFilesTree(filesys, h.opts)
+	if err != nil {
+		return err
+	}
+
+	h.tree.Store(tree)
+	return nil
+}
+
+// WithDirectoryPolicy sets how requests of a directory without an index.html are answered. Directory listings are
+// disabled by default and requests are answered with the fallback document (DirectoryPolicyFallback).
+// Use DirectoryPolicyNotFound or DirectoryPolicyForbidden to respond with an error, or DirectoryPolicyListing to opt in
+// to directory listings for internal tools.
+func WithDirectoryPolicy(policy DirectoryPolicy) staticFilesHandlerFunc {
+	return func(c staticFilesHandlerOpts) staticFilesHandlerOpts {
+		c.directoryPolicy = policy
+		return c
+	}
+}
+
+// EnvKeyReports returns the reports of the web env key analysis of the files in service, one per injected namespace.
+// It returns nil unless WithEnvKeyAnalysis is enabled.
+func (h *StaticFilesHandler) EnvKeyReports() []EnvKeyReport {
+	tree := h.tree.Load()
+	if tree == nil {
+		return nil
+	}
+	return tree.envKeyReports
 }
 
 func (h *StaticFilesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	tree := h.tree.Load()
 
 	// clean path for security and consistency
 	cleanedPath := path.Clean(r.URL.Path)
@@ -147,44 +483,167 @@ func (h *StaticFilesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// reconstitute the path
 	r.URL.Path = "/" + cleanedPath
 
+	// serve the web env endpoints
+	if endpoint, ok := h.endpoints[cleanedPath]; ok {
+		h.logger.logContext(ctx, slog.LevelDebug, "serve web env endpoint", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
+		serveWebEnvEndpoint(w, r, endpoint)
+		return
+	}
+
+	// proxy to the dev server
+	if h.proxy != nil {
+		h.serveDevServerProxy(w, r, cleanedPath)
+		return
+	}
+
 	// use root path for index.html
 	if r.URL.Path == "index.html" {
 		r.URL.Path = "/"
 	}
 
-	// handle non-root paths
-	if r.URL.Path != "/" {
-		// open file
-		file, err := h.mfilesys.Open(cleanedPath)
-		isErr := err != nil
-		isErrNotExist := errors.Is(err, os.ErrNotExist)
-		isFile := path.Ext(cleanedPath) != ""
-		if file != nil {
-			file.Close()
+	// the root path serves the entry document, without it the root is a directory without an index.html
+	if r.URL.Path == "/" {
+		if !fileExists(tree.mfilesys, h.opts.entryDocument) {
+			h.serveDirectory(w, r, tree, cleanedPath)
+			return
 		}
+		h.serveFile(w, r, tree, h.opts.entryDocument, true)
+		return
+	}
 
-		// return 500 for other errors
-		if isErr && !isErrNotExist {
-			h.logger.logContext(ctx, slog.LevelError, "could not open file", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
-			h.muxErrHandler(http.StatusInternalServerError, w, r)
-			return
+	// open file
+	file, err := tree.mfilesys.Open(cleanedPath)
+	isErr := err != nil
+	isErrNotExist := errors.Is(err, os.ErrNotExist)
+	isFile := path.Ext(cleanedPath) != ""
+	if file != nil {
+		file.Close()
+	}
+
+	// return 500 for other errors
+	if isErr && !isErrNotExist {
+		h.logger.logContext(ctx, slog.LevelError, "could not open file", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
+		h.muxErrHandler(http.StatusInternalServerError, w, r)
+		return
+	}
+
+	// return 404 for actual static file requests that don't exist
+	if isErrNotExist && isFile {
+		h.logger.logContext(ctx, slog.LevelDebug, "not found, static file", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
+		h.muxErrHandler(http.StatusNotFound, w, r)
+		return
+	}
+
+	// return 404 for sidecars requested directly if they should be hidden
+	if !isErr && h.opts.precompressed && h.opts.hideSidecars && isPrecompressedSidecar(tree.mfilesys, cleanedPath) {
+		h.logger.logContext(ctx, slog.LevelDebug, "not found, hidden sidecar", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
+		h.muxErrHandler(http.StatusNotFound, w, r)
+		return
+	}
+
+	// serve the fallback document and let SPA handle undefined routes
+	if isErrNotExist {
+		document := h.fallbackDocument(cleanedPath)
+		h.logger.logContext(ctx, slog.LevelDebug, "not found, serve fallback", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)}, slog.Attr{Key: "document", Value: slog.StringValue(document)})
+		h.serveFile(w, r, tree, document, true)
+		return
+	}
+
+	// serve the index.html of directories directly as the cleaned path never has a trailing slash
+	if index := path.Join(cleanedPath, "index.html"); fileExists(tree.mfilesys, index) {
+		h.serveFile(w, r, tree, index, h.isEntryDocument(index))
+		return
+	}
+
+	// never let the file server render a directory listing unless it is opted in
+	if info, err := fs.Stat(tree.mfilesys, cleanedPath); err == nil && info.IsDir() {
+		h.serveDirectory(w, r, tree, cleanedPath)
+		return
+	}
+
+	h.serveFile(w, r, tree, cleanedPath, h.isEntryDocument(cleanedPath))
+}
+
+// serveFile serves the named file of the tree
+//   - name: the file name relative to the root of the tree
+//   - isDocument: true if an entry document is served, either directly or as SPA fallback
+func (h *StaticFilesHandler) serveFile(w http.ResponseWriter, r *http.Request, tree *staticFilesTree, name string, isDocument bool) {
+	ctx := r.Context()
+
+	// a missing document must not let the file server render the listing of its directory
+	if isDocument && !fileExists(tree.mfilesys, name) {
+		h.logger.logContext(ctx, slog.LevelDebug, "not found, document", slog.Attr{Key: "document", Value: slog.StringValue(name)})
+		h.muxErrHandler(http.StatusNotFound, w, r)
+		return
+	}
+
+	// serve the file for another path (e.g. SPA fallback), the file server redirects direct index.html requests only
+	if r.URL.Path != "/"+name {
+		r.URL.Path = fileURLPath(name)
+	}
+
+	// set cache control according to the policy
+	if h.opts.cacheControl != nil {
+		if v := h.opts.cacheControl.value(name, isDocument); v != "" {
+			w.Header().Set("Cache-Control", v)
 		}
+	}
 
-		// return 404 for actual static file requests that don't exist
-		if isErrNotExist && isFile {
-			h.logger.logContext(ctx, slog.LevelDebug, "not found, static file", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
-			h.muxErrHandler(http.StatusNotFound, w, r)
-			return
+	// render documents which differ per request
+	if isDocument && h.needsRendering() {
+		h.serveRenderedDocument(w, r, tree, name)
+		return
+	}
+
+	// set the content security policy of documents
+	if isDocument && h.opts.csp != nil {
+		w.Header().Set("Content-Security-Policy", cspHeader(h.opts.csp.policy, tree.scriptHashes[name]))
+	}
+
+	// serve precompressed sidecar if the client accepts it
+	served := name
+	if h.opts.precompressed {
+		if sidecar, ok := servePrecompressed(tree.mfilesys, w, r, name); ok {
+			h.logger.logContext(ctx, slog.LevelDebug, "serve precompressed", slog.Attr{Key: "path", Value: slog.StringValue(sidecar)})
+			served = sidecar
+			r.URL.Path = "/" + sidecar
 		}
+	}
+
+	// set etag of the file which is actually served
+	if etag, ok := tree.etags[served]; ok {
+		w.Header().Set("ETag", etag)
+	}
 
-		// serve index.html and let SPA handle undefined routes
-		if isErrNotExist {
-			h.logger.logContext(ctx, slog.LevelDebug, "not found, serve index", slog.Attr{Key: "cleanedPath", Value: slog.StringValue(cleanedPath)})
-			r.URL.Path = "/"
+	tree.fileServer.ServeHTTP(w, r)
+}
+
+// fallbackDocument returns the document of the first fallback rule matching the path, defaults to the entry document
+func (h *StaticFilesHandler) fallbackDocument(cleanedPath string) string {
+	for _, rule := range h.opts.fallbacks {
+		if matchGlob(rule.pattern, cleanedPath) {
+			return rule.document
 		}
 	}
+	return h.opts.entryDocument
+}
 
-	h.fileServer.ServeHTTP(w, r)
+// isEntryDocument returns true if name is the entry document or a fallback document
+func (h *StaticFilesHandler) isEntryDocument(name string) bool {
+	for _, document := range h.opts.entryDocuments() {
+		if document == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fileURLPath returns the url path the file server serves the named file at, index.html is served by its directory
+func fileURLPath(name string) string {
+	if name == "index.html" || strings.HasSuffix(name, "/index.html") {
+		return "/" + strings.TrimSuffix(name, "index.html")
+	}
+	return "/" + name
 }
 
 // newMuxErrorHandler creates a new error handler function with the given muxErrHandler.
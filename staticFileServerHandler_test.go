@@ -2,14 +2,20 @@ package spaserve
 
 import (
 	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/psanford/memfs"
 )
 
 func TestStaticFilesHandler(t *testing.T) {
@@ -264,21 +270,56 @@ func TestStaticFilesHandler(t *testing.T) {
 
 				nswant := tc.ns
 				if tc.ns == "" {
-					nswant = defaultStaticFilesHandlerOpts.ns
+					nswant = defaultNamespace
+				}
+
+				if len(result.webEnvs) != 1 {
+					t.Fatalf("Expected one web environment to be injected, but got %d", len(result.webEnvs))
 				}
 
 				// Assert that the web environment is injected correctly
-				if result.webEnv != tc.webEnv {
-					t.Errorf("Expected web environment to be injected as %v, but got %v", env, result.webEnv)
+				if result.webEnvs[0].env != tc.webEnv {
+					t.Errorf("Expected web environment to be injected as %v, but got %v", env, result.webEnvs[0].env)
 				}
 
 				// Assert that the namespace is set correctly
-				if strings.Compare(result.ns, nswant) != 0 {
-					t.Errorf("Expected namespace to be set to %q, but got %q", nswant, result.ns)
+				if strings.Compare(result.webEnvs[0].ns, nswant) != 0 {
+					t.Errorf("Expected namespace to be set to %q, but got %q", nswant, result.webEnvs[0].ns)
 				}
 			})
 		}
 
+		t.Run("WithInjectWebEnv with nil env is a no-op", func(t *testing.T) {
+			if result := WithInjectWebEnv(nil, "")(staticFilesHandlerOpts{}); len(result.webEnvs) != 0 {
+				t.Fatalf("Expected no web environment to be injected, but got %d", len(result.webEnvs))
+			}
+
+			// without an index.html there is nothing to inject into, which must not fail
+			noIndexFilesys := memfs.New()
+			if err := noIndexFilesys.WriteFile("app.js", []byte("console.log('app');"), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			if _, err := NewStaticFilesHandler(noIndexFilesys, WithInjectWebEnv(nil, "")); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			handler, err := NewStaticFilesHandler(filesys, WithInjectWebEnv(nil, ""))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, but got %d", http.StatusOK, w.Code)
+			}
+			if strings.Contains(w.Body.String(), "window."+defaultNamespace) {
+				t.Errorf("Expected no web environment to be injected, but got %q", w.Body.String())
+			}
+		})
+
 		// Call the StaticFilesHandler function with the web environment
 		handler, err := NewStaticFilesHandler(filesys, WithInjectWebEnv(env, namespace))
 		if err != nil {
@@ -302,4 +343,467 @@ func TestStaticFilesHandler(t *testing.T) {
 		}
 
 	})
+	t.Run("Serve WithPrecompressed", func(t *testing.T) {
+		precompressedFilesys := os.DirFS(path.Join("testdata", "precompressed"))
+
+		tt := []struct {
+			name           string
+			path           string
+			acceptEncoding string
+			hideSidecars   bool
+			wantCode       int
+			wantEncoding   string
+		}{
+			{
+				name:           "prefers brotli",
+				path:           "/app.js",
+				acceptEncoding: "gzip, deflate, br",
+				wantCode:       http.StatusOK,
+				wantEncoding:   "br",
+			},
+			{
+				name:           "falls back to gzip",
+				path:           "/app.js",
+				acceptEncoding: "gzip, br;q=0",
+				wantCode:       http.StatusOK,
+				wantEncoding:   "gzip",
+			},
+			{
+				name:           "serves original without accept encoding",
+				path:           "/app.js",
+				acceptEncoding: "",
+				wantCode:       http.StatusOK,
+				wantEncoding:   "",
+			},
+			{
+				name:           "serves index without sidecar",
+				path:           "/",
+				acceptEncoding: "gzip, br",
+				wantCode:       http.StatusOK,
+				wantEncoding:   "",
+			},
+			{
+				name:           "serves sidecar directly",
+				path:           "/app.js.gz",
+				acceptEncoding: "",
+				wantCode:       http.StatusOK,
+				wantEncoding:   "",
+			},
+			{
+				name:           "hides sidecar",
+				path:           "/app.js.gz",
+				acceptEncoding: "",
+				hideSidecars:   true,
+				wantCode:       http.StatusNotFound,
+				wantEncoding:   "",
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				handler, err := NewStaticFilesHandler(precompressedFilesys, WithPrecompressed(tc.hideSidecars))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				if w.Code != tc.wantCode {
+					t.Errorf("Expected status code %d, but got %d", tc.wantCode, w.Code)
+				}
+
+				if got := w.Header().Get("Content-Encoding"); got != tc.wantEncoding {
+					t.Errorf("Expected Content-Encoding %q, but got %q", tc.wantEncoding, got)
+				}
+
+				if tc.wantEncoding != "" {
+					if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/javascript") {
+						t.Errorf("Expected Content-Type of the original file, but got %q", got)
+					}
+					if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+						t.Errorf("Expected Vary %q, but got %q", "Accept-Encoding", got)
+					}
+				}
+			})
+		}
+	})
+	t.Run("Serve WithCompression", func(t *testing.T) {
+		compressFilesys := memfs.New()
+		_ = compressFilesys.WriteFile("index.html", []byte("<html><head></head><body>"+strings.Repeat("<p>hello</p>", 64)+"</body></html>"), 0644)
+
+		env := struct {
+			Name string `json:"name"`
+		}{
+			Name: "compressed",
+		}
+
+		handler, err := NewStaticFilesHandler(compressFilesys, WithCompression(), WithInjectWebEnv(env, ""))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/page", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, but got %d", http.StatusOK, w.Code)
+		}
+
+		if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("Expected Content-Encoding %q, but got %q", "gzip", got)
+		}
+
+		zr, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// Assert that the compressed index.html contains the injected web environment
+		if !strings.Contains(string(body), env.Name) {
+			t.Errorf("Expected web environment to be injected, but got %q", body)
+		}
+	})
+	t.Run("Serve without stale sidecars of injected documents", func(t *testing.T) {
+		index := []byte("<html><head></head><body>" + strings.Repeat("<p>hello</p>", 64) + "</body></html>")
+		staleIndex, err := gzipData(index)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		staleFilesys := memfs.New()
+		_ = staleFilesys.WriteFile("index.html", index, 0644)
+		_ = staleFilesys.WriteFile("index.html.gz", staleIndex, 0644)
+		_ = staleFilesys.WriteFile("index.html.br", []byte("stale"), 0644)
+
+		env := struct {
+			Name string `json:"name"`
+		}{
+			Name: "fresh",
+		}
+
+		tt := []struct {
+			name         string
+			opt          staticFilesHandlerFunc
+			wantEncoding string
+		}{
+			{name: "drops sidecars WithPrecompressed", opt: WithPrecompressed(false), wantEncoding: ""},
+			{name: "regenerates gzip WithCompression", opt: WithCompression(), wantEncoding: "gzip"},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				handler, err := NewStaticFilesHandler(staleFilesys, tc.opt, WithInjectWebEnv(env, ""))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Accept-Encoding", "gzip, br")
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				if got := w.Header().Get("Content-Encoding"); got != tc.wantEncoding {
+					t.Fatalf("Expected Content-Encoding %q, but got %q", tc.wantEncoding, got)
+				}
+
+				body := w.Body.Bytes()
+				if tc.wantEncoding == "gzip" {
+					zr, err := gzip.NewReader(w.Body)
+					if err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+					if body, err = io.ReadAll(zr); err != nil {
+						t.Fatalf("Unexpected error: %v", err)
+					}
+				}
+
+				// Assert that the served index.html contains the injected web environment
+				if !strings.Contains(string(body), env.Name) {
+					t.Errorf("Expected web environment to be injected, but got %q", body)
+				}
+			})
+		}
+	})
+	t.Run("Serve WithCacheControl", func(t *testing.T) {
+		cacheFilesys := memfs.New()
+		_ = cacheFilesys.MkdirAll("assets", 0755)
+		_ = cacheFilesys.WriteFile("index.html", []byte("<html><head></head><body></body></html>"), 0644)
+		_ = cacheFilesys.WriteFile("assets/index-BxY3k9aQ.js", []byte("console.log('hello');"), 0644)
+		_ = cacheFilesys.WriteFile("robots.txt", []byte("User-agent: *"), 0644)
+
+		handler, err := NewStaticFilesHandler(cacheFilesys, WithCacheControl(DefaultCacheControlPolicy()))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		tt := []struct {
+			path string
+			want string
+		}{
+			{path: "/", want: "no-cache"},
+			{path: "/some/route", want: "no-cache"},
+			{path: "/assets/index-BxY3k9aQ.js", want: "public, max-age=31536000, immutable"},
+			{path: "/robots.txt", want: ""},
+		}
+
+		for _, tc := range tt {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d for %s, but got %d", http.StatusOK, tc.path, w.Code)
+			}
+
+			if got := w.Header().Get("Cache-Control"); got != tc.want {
+				t.Errorf("Expected Cache-Control %q for %s, but got %q", tc.want, tc.path, got)
+			}
+		}
+	})
+	t.Run("Serve WithETags", func(t *testing.T) {
+		newHandler := func(env any) http.Handler {
+			handler, err := NewStaticFilesHandler(filesys, WithETags(), WithInjectWebEnv(env, ""))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			return handler
+		}
+
+		getETag := func(handler http.Handler, p string) string {
+			req := httptest.NewRequest(http.MethodGet, p, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, but got %d", http.StatusOK, w.Code)
+			}
+			return w.Header().Get("ETag")
+		}
+
+		handler := newHandler(map[string]string{"name": "a"})
+
+		etag := getETag(handler, "/file.txt")
+		if etag == "" {
+			t.Fatal("Expected ETag to be set")
+		}
+
+		// Assert that the validators are identical across instances
+		if got := getETag(newHandler(map[string]string{"name": "a"}), "/"); got != getETag(handler, "/") {
+			t.Errorf("Expected identical index ETag across instances, but got %q", got)
+		}
+
+		// Assert that the index ETag reflects the injected web environment
+		if got := getETag(newHandler(map[string]string{"name": "b"}), "/"); got == getETag(handler, "/") {
+			t.Errorf("Expected index ETag to change with the web environment, but got %q", got)
+		}
+
+		// Assert that the SPA fallback serves the index ETag
+		if got := getETag(handler, "/page"); got != getETag(handler, "/") {
+			t.Errorf("Expected fallback ETag to match the index ETag, but got %q", got)
+		}
+
+		// Assert that If-None-Match is honored
+		req := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotModified {
+			t.Errorf("Expected status code %d, but got %d", http.StatusNotModified, w.Code)
+		}
+	})
+	t.Run("Reload", func(t *testing.T) {
+		newFilesys := func(body string) *memfs.FS {
+			mfs := memfs.New()
+			_ = mfs.WriteFile("index.html", []byte("<html><head></head><body>"+body+"</body></html>"), 0644)
+			return mfs
+		}
+
+		getBody := func(handler http.Handler) string {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w.Body.String()
+		}
+
+		handler, err := NewStaticFilesHandler(newFilesys("v1"), WithInjectWebEnv(map[string]string{"name": "test"}, ""))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		// Assert that concurrent requests are served while reloading
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if body := getBody(handler); !strings.Contains(body, "window.APP_ENV") {
+						t.Errorf("Expected web environment to be injected, but got %q", body)
+						return
+					}
+				}
+			}()
+		}
+		for i := 0; i < 10; i++ {
+			if err := handler.Reload(newFilesys(fmt.Sprintf("v%d", i))); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}
+		wg.Wait()
+
+		if err := handler.Reload(newFilesys("v2")); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if body := getBody(handler); !strings.Contains(body, "v2") {
+			t.Errorf("Expected reloaded content, but got %q", body)
+		}
+
+		// Assert that a failed build keeps the previous files in service
+		if err := handler.Reload(memfs.New()); !errors.Is(err, ErrNoIndexFound) {
+			t.Errorf("Expected ErrNoIndexFound, but got %v", err)
+		}
+		if body := getBody(handler); !strings.Contains(body, "v2") {
+			t.Errorf("Expected previous content to stay in service, but got %q", body)
+		}
+	})
+	t.Run("Serve WithFallback", func(t *testing.T) {
+		fallbackFilesys := memfs.New()
+		_ = fallbackFilesys.MkdirAll("admin", 0755)
+		_ = fallbackFilesys.WriteFile("main.html", []byte("<html><head></head><body>main</body></html>"), 0644)
+		_ = fallbackFilesys.WriteFile("admin/index.html", []byte("<html><head></head><body>admin</body></html>"), 0644)
+		_ = fallbackFilesys.WriteFile("embed.html", []byte("<html><head></head><body>embed</body></html>"), 0644)
+
+		handler, err := NewStaticFilesHandler(fallbackFilesys,
+			WithEntryDocument("main.html"),
+			WithFallback("/admin/**", "admin/index.html"),
+			WithFallback("/embed/**", "/embed.html"),
+			WithInjectWebEnv(map[string]string{"name": "test"}, ""),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		tt := []struct {
+			path     string
+			wantCode int
+			wantBody string
+		}{
+			{path: "/", wantCode: http.StatusOK, wantBody: "main"},
+			{path: "/some/route", wantCode: http.StatusOK, wantBody: "main"},
+			{path: "/admin", wantCode: http.StatusOK, wantBody: "admin"},
+			{path: "/admin/users/1", wantCode: http.StatusOK, wantBody: "admin"},
+			{path: "/embed/widget", wantCode: http.StatusOK, wantBody: "embed"},
+			{path: "/embed.html", wantCode: http.StatusOK, wantBody: "embed"},
+			{path: "/admin/missing.js", wantCode: http.StatusNotFound, wantBody: ""},
+		}
+
+		for _, tc := range tt {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.wantCode {
+				t.Errorf("Expected status code %d for %s, but got %d", tc.wantCode, tc.path, w.Code)
+			}
+
+			if tc.wantCode != http.StatusOK {
+				continue
+			}
+
+			if !strings.Contains(w.Body.String(), tc.wantBody) {
+				t.Errorf("Expected %s to serve %q, but got %q", tc.path, tc.wantBody, w.Body.String())
+			}
+
+			// Assert that the web environment is injected into every entry document
+			if !strings.Contains(w.Body.String(), "window.APP_ENV") {
+				t.Errorf("Expected web environment to be injected for %s, but got %q", tc.path, w.Body.String())
+			}
+		}
+
+		// Assert that missing fallback documents fail at startup
+		if _, err := NewStaticFilesHandler(fallbackFilesys, WithFallback("/missing/**", "missing.html")); !errors.Is(err, ErrNoIndexFound) {
+			t.Errorf("Expected ErrNoIndexFound, but got %v", err)
+		}
+	})
+}
+
+func TestStaticFilesHandlerWithMultipleWebEnvs(t *testing.T) {
+	fsys := memfs.New()
+	_ = fsys.WriteFile("index.html", []byte(`<html><head><title>app</title></head><body></body></html>`), 0644)
+
+	t.Run("injects namespaces in option order", func(t *testing.T) {
+		handler, err := NewStaticFilesHandler(fsys,
+			WithInjectWebEnv(map[string]string{"api": "/api"}, "APP_ENV"),
+			WithInjectWebEnv(map[string]bool{"beta": true}, "FEATURE_FLAGS", WithInjectFormat(InjectFormatJSON)),
+			WithInjectWebEnv(map[string]string{"version": "1.0.0"}, "BUILD_INFO", WithInjectPosition(InjectAtBodyEnd())),
+		)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		want := `<html><head>` +
+			`<script type="text/javascript">window.APP_ENV = {"api":"/api"};</script>` +
+			`<script type="application/json" id="FEATURE_FLAGS">{"beta":true}</script>` +
+			`<title>app</title></head><body>` +
+			`<script type="text/javascript">window.BUILD_INFO = {"version":"1.0.0"};</script>` +
+			`</body></html>`
+		if w.Body.String() != want {
+			t.Errorf("Expected body %s, but got %s", want, w.Body.String())
+		}
+	})
+
+	tt := []struct {
+		name string
+		fn   []staticFilesHandlerFunc
+	}{
+		{
+			name: "colliding namespaces",
+			fn: []staticFilesHandlerFunc{
+				WithInjectWebEnv(map[string]string{"a": "a"}, "APP_ENV"),
+				WithInjectWebEnv(map[string]string{"b": "b"}, " APP_ENV "),
+			},
+		},
+		{
+			name: "default namespace collides",
+			fn: []staticFilesHandlerFunc{
+				WithInjectWebEnv(map[string]string{"a": "a"}, ""),
+				WithInjectWebEnv(map[string]string{"b": "b"}, "APP_ENV"),
+			},
+		},
+		{
+			name: "provider namespace collides",
+			fn: []staticFilesHandlerFunc{
+				WithInjectWebEnv(map[string]string{"a": "a"}, "APP_ENV"),
+				WithWebEnvProvider(func(*http.Request) (string, any, error) { return "", nil, nil }, "APP_ENV"),
+			},
+		},
+		{
+			name: "dev server namespaces collide",
+			fn: []staticFilesHandlerFunc{
+				WithInjectWebEnv(map[string]string{"a": "a"}, "APP_ENV"),
+				WithInjectWebEnv(map[string]string{"b": "b"}, "APP_ENV"),
+				WithDevServerProxy("http://localhost:5173"),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewStaticFilesHandler(fsys, tc.fn...); !errors.Is(err, ErrDuplicateNamespace) {
+				t.Errorf("Expected error %v, but got %v", ErrDuplicateNamespace, err)
+			}
+		})
+	}
 }
@@ -0,0 +1,164 @@
+package spaserve
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/psanford/memfs"
+)
+
+// staticFilesTree is a built memfs together with everything derived from it, it is never modified once built
+type staticFilesTree struct {
+	mfilesys      *memfs.FS
+	fileServer    http.Handler
+	etags         map[string]string
+	scriptHashes  map[string][]string
+	variants      *variantCache
+	envKeyReports []EnvKeyReport
+}
+
+// buildStaticFilesTree copies the given file system into a memfs and runs the build steps enabled by the options
+func buildStaticFilesTree(filesys fs.FS, opts staticFilesHandlerOpts) (*staticFilesTree, error) {
+	// fallback documents have to exist as they are served for undefined routes
+	for _, rule := range opts.fallbacks {
+		if !fileExists(filesys, rule.document) {
+			return nil, errors.Join(ErrNoIndexFound, errors.New(rule.document))
+		}
+	}
+
+	var hooks []OnHookFunc
+
+	// replace placeholders first so placeholders in documents are replaced before the web env is injected
+	var replacer *PlaceholderReplacer
+	if opts.placeholders != nil {
+		var err error
+		if replacer, err = NewPlaceholderReplacer(opts.placeholders, opts.placeholderPrefix); err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, replacer.Hook)
+	}
+
+	// scan the bundles for references of the web env keys
+	var analyzers []*EnvKeyAnalyzer
+	if opts.envKeyAnalysis {
+		for _, injection := range opts.webEnvs {
+			analyzer, err := NewEnvKeyAnalyzer(injection.env, injection.ns)
+			if err != nil {
+				return nil, err
+			}
+			analyzers = append(analyzers, analyzer)
+			hooks = append(hooks, analyzer.Hook)
+		}
+	}
+
+	// inject web envs if provided
+	if len(opts.webEnvs) > 0 {
+		hook, err := webEnvHook(filesys, opts.webEnvs, opts.entryDocuments(), opts.logger)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	// drop the sidecars of files the hooks modify as they still hold the original content
+	modified := newModifiedFiles(chainHooks(hooks...))
+	mfilesys, err := copyFileSys(filesys, modified.hook, modified.isStaleSidecar)
+	if err != nil {
+		return nil, err
+	}
+
+	// report placeholders which are likely misconfigured
+	if replacer != nil {
+		logPlaceholderReport(newLogger(opts.logger), replacer.Report())
+	}
+
+	// report web env keys which are likely misconfigured
+	var envKeyReports []EnvKeyReport
+	for _, analyzer := range analyzers {
+		envKeyReports = append(envKeyReports, analyzer.Report())
+	}
+	logEnvKeyReports(newLogger(opts.logger), envKeyReports)
+	if opts.envKeyStrict {
+		if err := missingEnvKeys(envKeyReports); err != nil {
+			return nil, err
+		}
+	}
+
+	// compress files after injection so the rewritten documents are covered, their stale sidecars are dropped
+	if opts.compress {
+		if err := CompressFileSys(mfilesys); err != nil {
+			return nil, err
+		}
+	}
+
+	// compute etags last so they reflect the final content
+	var etags map[string]string
+	if opts.etags {
+		if etags, err = ComputeETags(mfilesys); err != nil {
+			return nil, err
+		}
+	}
+
+	// hash the inline scripts of the documents for the content security policy
+	var scriptHashes map[string][]string
+	if opts.csp != nil && opts.csp.mode == CSPModeHash {
+		scriptHashes = map[string][]string{}
+		for _, document := range opts.entryDocuments() {
+			data, err := fs.ReadFile(mfilesys, document)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return nil, errors.Join(ErrCouldNotReadFile, err)
+			}
+			if scriptHashes[document], err = inlineScriptHashes(data); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &staticFilesTree{
+		mfilesys:      mfilesys,
+		fileServer:    newFileServer(mfilesys, opts.directoryPolicy),
+		etags:         etags,
+		scriptHashes:  scriptHashes,
+		variants:      newVariantCache(),
+		envKeyReports: envKeyReports,
+	}, nil
+}
+
+// modifiedFiles records the files whose data is modified by a hook
+type modifiedFiles struct {
+	onHook   OnHookFunc
+	modified map[string]bool
+}
+
+// newModifiedFiles returns a recorder of the files modified by the hook
+func newModifiedFiles(onHook OnHookFunc) *modifiedFiles {
+	return &modifiedFiles{onHook: onHook, modified: map[string]bool{}}
+}
+
+// hook runs the wrapped hook and records whether it modified the file, it can be used as OnHookFunc
+func (m *modifiedFiles) hook(p string, data []byte) ([]byte, error) {
+	out, err := m.onHook(p, data)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(out, data) {
+		m.modified[p] = true
+	}
+	return out, nil
+}
+
+// isStaleSidecar returns true if p is a precompressed sidecar of a modified file
+func (m *modifiedFiles) isStaleSidecar(p string) bool {
+	for _, enc := range precompressedEncodings {
+		if original, ok := strings.CutSuffix(p, enc.ext); ok && m.modified[original] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package spaserve
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+var tsIdentifierRegex = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+
+// GenerateTypeScript returns a TypeScript declaration file (.d.ts) declaring the web environment on window under the
+// namespace, so the frontend types can not drift from the Go struct passed to WithInjectWebEnv.
+// The declaration follows the json struct tags: renamed and skipped fields, omitempty fields are optional, pointers
+// are nullable and nested named structs become interfaces.
+//   - conf: the web environment or a zero value of its type (e.g. Env{})
+//   - ns: the namespace of the web environment, defaults to "APP_ENV"
+func GenerateTypeScript(conf any, ns string) ([]byte, error) {
+	if ns == "" {
+		ns = defaultNamespace
+	}
+	ns, err := validateNamespace(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	// a secret must not even be declared
+	if _, err := checkSecrets(conf); err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(conf)
+	if t == nil {
+		return nil, errors.Join(ErrCouldNotGenerateTypes, errors.New("config must not be nil"))
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	g := &tsGenerator{names: map[reflect.Type]string{}, used: map[string]bool{}}
+	rootName := tsTypeName(ns[strings.LastIndex(ns, ".")+1:])
+	var rootType string
+	if t.Kind() == reflect.Struct {
+		g.used[rootName] = true
+		g.names[t] = rootName
+		g.queue = append(g.queue, t)
+		rootType = rootName
+	} else {
+		rootType = g.tsType(t)
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by spaserve. DO NOT EDIT.\n\n")
+
+	// interfaces are exported in the order they are found, which starts with the root
+	for i := 0; i < len(g.queue); i++ {
+		g.writeInterface(&b, g.queue[i])
+		b.WriteString("\n")
+	}
+
+	b.WriteString("declare global {\n  interface Window {\n")
+	segments := strings.Split(ns, ".")
+	for i, segment := range segments {
+		b.WriteString(strings.Repeat("  ", i+2) + tsPropertyName(segment) + ": ")
+		if i < len(segments)-1 {
+			b.WriteString("{\n")
+			continue
+		}
+		b.WriteString(rootType + ";\n")
+	}
+	for i := len(segments) - 2; i >= 0; i-- {
+		b.WriteString(strings.Repeat("  ", i+2) + "};\n")
+	}
+	b.WriteString("  }\n}\n")
+
+	// declare global is only allowed in modules, which needs an export even if no interface is declared
+	b.WriteString("\nexport {};\n")
+
+	if g.err != nil {
+		return nil, g.err
+	}
+	return []byte(b.String()), nil
+}
+
+// tsGenerator collects the named struct types which are declared as interfaces
+type tsGenerator struct {
+	names map[reflect.Type]string
+	used  map[string]bool
+	queue []reflect.Type
+	err   error
+}
+
+// tsType returns the TypeScript type of the Go type as marshalled by encoding/json
+func (g *tsGenerator) tsType(t reflect.Type) string {
+	switch {
+	case t.Kind() == reflect.Pointer:
+		return g.tsType(t.Elem()) + " | null"
+	case t == timeType:
+		return "string"
+	case t == rawMessageType:
+		return "unknown"
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return "unknown"
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Interface:
+		return "unknown"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			// []byte is marshalled as base64 string
+			return "string"
+		}
+		elem := g.tsType(t.Elem())
+		if strings.Contains(elem, "|") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case reflect.Map:
+		return "Record<string, " + g.tsType(t.Elem()) + ">"
+	case reflect.Struct:
+		if t.Name() == "" {
+			var b strings.Builder
+			b.WriteString("{ ")
+			for _, field := range g.tsFields(t) {
+				b.WriteString(field + "; ")
+			}
+			b.WriteString("}")
+			return b.String()
+		}
+		return g.interfaceName(t)
+	}
+
+	g.err = errors.Join(g.err, ErrCouldNotGenerateTypes, errors.New("unsupported type "+t.String()))
+	return "never"
+}
+
+// interfaceName returns the unique interface name of the named struct type and queues its declaration
+func (g *tsGenerator) interfaceName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	base := tsTypeName(t.Name())
+	name := base
+	for i := 2; g.used[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.used[name] = true
+	g.names[t] = name
+	g.queue = append(g.queue, t)
+	return name
+}
+
+// writeInterface writes the interface declaration of the struct type
+func (g *tsGenerator) writeInterface(b *strings.Builder, t reflect.Type) {
+	b.WriteString("export interface " + g.names[t] + " {\n")
+	for _, field := range g.tsFields(t) {
+		b.WriteString("  " + field + ";\n")
+	}
+	b.WriteString("}\n")
+}
+
+// tsFields returns the property declarations of the struct fields as marshalled by encoding/json
+func (g *tsGenerator) tsFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// embedded structs without a name are flattened into the parent
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, g.tsFields(ft)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		optional := ""
+		typ := ""
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty", "omitzero":
+				optional = "?"
+			case "string":
+				if isScalarKind(field.Type) {
+					typ = "string"
+				}
+			}
+		}
+		if typ == "" {
+			typ = g.tsType(field.Type)
+		}
+
+		fields = append(fields, tsPropertyName(name)+optional+": "+typ)
+	}
+	return fields
+}
+
+// isScalarKind returns true if the json ",string" option applies to the type
+func isScalarKind(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// tsPropertyName returns the property name, quoted unless it is a valid identifier
+func tsPropertyName(name string) string {
+	if tsIdentifierRegex.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// tsTypeName converts a name to PascalCase (e.g. APP_ENV becomes AppEnv and config becomes Config)
+func tsTypeName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		if part == strings.ToUpper(part) {
+			part = strings.ToLower(part)
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	if b.Len() == 0 {
+		return "Env"
+	}
+	return b.String()
+}
@@ -0,0 +1,154 @@
+package spaserve
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type tsFeature struct {
+	Name     string       `json:"name"`
+	Children []*tsFeature `json:"children,omitempty"`
+}
+
+type tsBase struct {
+	Version string `json:"version"`
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	type env struct {
+		tsBase
+		APIURL   string            `json:"apiUrl"`
+		Retries  int               `json:"retries,omitempty"`
+		Debug    bool              `json:"debug"`
+		Flags    map[string]bool   `json:"flags"`
+		Features []tsFeature       `json:"features"`
+		Primary  *tsFeature        `json:"primary"`
+		Build    time.Time         `json:"build"`
+		Theme    struct{ A int }   `json:"theme"`
+		Count    int64             `json:"count,string"`
+		Extra    any               `json:"extra"`
+		Labels   map[string]string `json:"x-labels"`
+		Ignored  string            `json:"-"`
+		NoTag    string
+		private  string
+	}
+
+	tt := []struct {
+		name string
+		conf any
+		ns   string
+		want string
+	}{
+		{
+			name: "struct",
+			conf: env{},
+			ns:   "",
+			want: `// Code generated by spaserve. DO NOT EDIT.
+
+export interface AppEnv {
+  version: string;
+  apiUrl: string;
+  retries?: number;
+  debug: boolean;
+  flags: Record<string, boolean>;
+  features: TsFeature[];
+  primary: TsFeature | null;
+  build: string;
+  theme: { A: number; };
+  count: string;
+  extra: unknown;
+  "x-labels": Record<string, string>;
+  NoTag: string;
+}
+
+export interface TsFeature {
+  name: string;
+  children?: (TsFeature | null)[];
+}
+
+declare global {
+  interface Window {
+    APP_ENV: AppEnv;
+  }
+}
+
+export {};
+`,
+		},
+		{
+			name: "dotted namespace",
+			conf: &tsBase{},
+			ns:   "__SHELL__.apps.billing",
+			want: `// Code generated by spaserve. DO NOT EDIT.
+
+export interface Billing {
+  version: string;
+}
+
+declare global {
+  interface Window {
+    __SHELL__: {
+      apps: {
+        billing: Billing;
+      };
+    };
+  }
+}
+
+export {};
+`,
+		},
+		{
+			name: "map",
+			conf: map[string]int{},
+			ns:   "FEATURE_FLAGS",
+			want: `// Code generated by spaserve. DO NOT EDIT.
+
+declare global {
+  interface Window {
+    FEATURE_FLAGS: Record<string, number>;
+  }
+}
+
+export {};
+`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := GenerateTypeScript(tc.conf, tc.ns)
+			if err != nil {
+				t.Fatalf("GenerateTypeScript() returned an unexpected error: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("GenerateTypeScript() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateTypeScriptErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		conf    any
+		ns      string
+		wantErr error
+	}{
+		{name: "nil config", conf: nil, wantErr: ErrCouldNotGenerateTypes},
+		{name: "unsupported type", conf: struct{ C chan int }{}, wantErr: ErrCouldNotGenerateTypes},
+		{name: "invalid namespace", conf: struct{}{}, ns: "a-b", wantErr: ErrCouldNotParseNamespace},
+		{name: "secret field", conf: struct {
+			Password string `spaserve:"secret"`
+		}{}, wantErr: ErrSecretField},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := GenerateTypeScript(tc.conf, tc.ns); !errors.Is(err, tc.wantErr) {
+				t.Errorf("GenerateTypeScript() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package spaserve
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// watch polls the file system and swaps in a rebuilt tree whenever it changed and settled
+//   - built: the snapshot of the file system the current tree was built from
+func (h *StaticFilesHandler) watch(ctx context.Context, filesys fs.FS, interval time.Duration, built uint64) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	pending := built
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		snapshot, err := snapshotFileSys(filesys)
+		if err != nil {
+			h.logger.logContext(ctx, slog.LevelError, "could not snapshot file system", slog.Attr{Key: "error", Value: slog.AnyValue(err)})
+			continue
+		}
+
+		// nothing changed since the last build
+		if snapshot == built {
+			pending = snapshot
+			continue
+		}
+
+		// wait for the file system to settle as build tools write many files
+		if snapshot != pending {
+			pending = snapshot
+			continue
+		}
+
+		if err := h.Reload(filesys); err != nil {
+			h.logger.logContext(ctx, slog.LevelError, "could not rebuild files", slog.Attr{Key: "error", Value: slog.AnyValue(err)})
+			// do not retry until the file system changes again
+			built = snapshot
+			continue
+		}
+
+		built = snapshot
+		h.logger.logContext(ctx, slog.LevelInfo, "rebuilt files")
+	}
+}
+
+// snapshotFileSys returns a fingerprint of the paths, sizes and modification times of the file system
+func snapshotFileSys(filesys fs.FS) (uint64, error) {
+	hash := fnv.New64a()
+	err := fs.WalkDir(filesys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Join(ErrUnexpectedWalkError, err)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return errors.Join(ErrUnexpectedWalkError, err)
+		}
+
+		_, _ = hash.Write([]byte(p))
+		_, _ = hash.Write([]byte{0})
+		_, _ = hash.Write([]byte(strconv.FormatInt(info.Size(), 10)))
+		_, _ = hash.Write([]byte(strconv.FormatInt(info.ModTime().UnixNano(), 10)))
+		_, _ = hash.Write([]byte(info.Mode().String()))
+		_, _ = hash.Write([]byte{0})
+		return nil
+	})
+	return hash.Sum64(), err
+}
@@ -0,0 +1,106 @@
+package spaserve
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use by the watcher and the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html><head></head><body>v1</body></html>"), 0o644); err != nil {
+		t.Fatalf("os.WriteFile() returned an unexpected error: %v", err)
+	}
+
+	logs := &syncBuffer{}
+	logger := slog.New(slog.NewJSONHandler(logs, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler, err := NewStaticFilesHandler(os.DirFS(dir),
+		WithWatch(ctx, 10*time.Millisecond),
+		WithLogger(logger),
+		WithInjectWebEnv(map[string]string{"name": "test"}, ""),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	body := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	waitFor := func(cond func() bool) bool {
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if cond() {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	if !strings.Contains(body(), "v1") {
+		t.Fatalf("Expected initial content, but got %q", body())
+	}
+
+	t.Run("rebuilds on change", func(t *testing.T) {
+		if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html><head></head><body>version2</body></html>"), 0o644); err != nil {
+			t.Fatalf("os.WriteFile() returned an unexpected error: %v", err)
+		}
+
+		if !waitFor(func() bool { return strings.Contains(body(), "version2") }) {
+			t.Fatalf("Expected rebuilt content, but got %q", body())
+		}
+
+		// Assert that the web environment is injected into the rebuilt index
+		if !strings.Contains(body(), "window.APP_ENV") {
+			t.Errorf("Expected web environment to be injected, but got %q", body())
+		}
+	})
+
+	t.Run("keeps serving on rebuild error", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(dir, "index.html")); err != nil {
+			t.Fatalf("os.Remove() returned an unexpected error: %v", err)
+		}
+
+		if !waitFor(func() bool { return strings.Contains(logs.String(), "could not rebuild files") }) {
+			t.Fatalf("Expected rebuild error to be logged, but got %q", logs.String())
+		}
+
+		if !strings.Contains(body(), "version2") {
+			t.Errorf("Expected previous content to stay in service, but got %q", body())
+		}
+	})
+}
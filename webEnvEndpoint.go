@@ -0,0 +1,92 @@
+package spaserve
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebEnvFormat is the format a web env endpoint serves the web environment in.
+type WebEnvFormat int
+
+const (
+	// WebEnvFormatScript serves a classic script assigning the env to window (e.g. window.APP_ENV = {...};)
+	WebEnvFormatScript WebEnvFormat = iota
+	// WebEnvFormatModule serves an ES module exporting the env (e.g. export default {...};)
+	WebEnvFormatModule
+	// WebEnvFormatJSON serves the env as plain JSON
+	WebEnvFormatJSON
+)
+
+// webEnvEndpoint serves the web environment at a path instead of injecting it into the html
+type webEnvEndpoint struct {
+	path   string
+	format WebEnvFormat
+	env    any
+	ns     string
+}
+
+// renderedWebEnvEndpoint is a web env endpoint with its body rendered at startup
+type renderedWebEnvEndpoint struct {
+	contentType string
+	body        []byte
+}
+
+// renderWebEnvEndpoints validates and renders the bodies of the endpoints keyed by their path
+func renderWebEnvEndpoints(endpoints []webEnvEndpoint, logger *servespaLogger) (map[string]renderedWebEnvEndpoint, error) {
+	rendered := map[string]renderedWebEnvEndpoint{}
+	for _, endpoint := range endpoints {
+		if _, ok := rendered[endpoint.path]; ok {
+			return nil, errors.Join(ErrDuplicateWebEnvEndpoint, errors.New(endpoint.path))
+		}
+
+		ns, err := validateNamespace(endpoint.ns)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := validateSecrets(logger, ns, endpoint.env); err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(endpoint.env)
+		if err != nil {
+			return nil, errors.Join(ErrCouldNotMarshalConfig, err)
+		}
+
+		switch endpoint.format {
+		case WebEnvFormatModule:
+			rendered[endpoint.path] = renderedWebEnvEndpoint{
+				contentType: "text/javascript; charset=utf-8",
+				body:        []byte("export default " + string(b) + ";\n"),
+			}
+		case WebEnvFormatJSON:
+			rendered[endpoint.path] = renderedWebEnvEndpoint{
+				contentType: "application/json",
+				body:        b,
+			}
+		default:
+			rendered[endpoint.path] = renderedWebEnvEndpoint{
+				contentType: "text/javascript; charset=utf-8",
+				body:        []byte(windowAssignment(ns, b) + "\n"),
+			}
+		}
+	}
+	return rendered, nil
+}
+
+// serveWebEnvEndpoint serves the web env endpoint with no-cache headers
+func serveWebEnvEndpoint(w http.ResponseWriter, r *http.Request, endpoint renderedWebEnvEndpoint) {
+	w.Header().Set("Content-Type", endpoint.contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", contentETag(endpoint.body))
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(endpoint.body))
+}
+
+// normalizeEndpointPath returns the endpoint path relative to the base path without leading slash
+func normalizeEndpointPath(p string) string {
+	return strings.Trim(p, "/")
+}
@@ -0,0 +1,94 @@
+package spaserve
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestWebEnvEndpoint(t *testing.T) {
+	filesys := os.DirFS(path.Join("testdata", "files"))
+
+	env := struct {
+		Name string `json:"name"`
+	}{
+		Name: "test",
+	}
+
+	handler, err := NewStaticFilesHandler(filesys,
+		WithBasePath("/app"),
+		WithWebEnvEndpoint("/env.js", WebEnvFormatScript, env, "TEST_ENV"),
+		WithWebEnvEndpoint("env.mjs", WebEnvFormatModule, env, ""),
+		WithWebEnvEndpoint("config/env.json", WebEnvFormatJSON, env, ""),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		path            string
+		wantContentType string
+		wantBody        string
+	}{
+		{path: "/app/env.js", wantContentType: "text/javascript; charset=utf-8", wantBody: "window.TEST_ENV = {\"name\":\"test\"};\n"},
+		{path: "/app/env.mjs", wantContentType: "text/javascript; charset=utf-8", wantBody: "export default {\"name\":\"test\"};\n"},
+		{path: "/app/config/env.json", wantContentType: "application/json", wantBody: "{\"name\":\"test\"}"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status code %d, but got %d", http.StatusOK, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tc.wantContentType {
+				t.Errorf("Expected Content-Type %q, but got %q", tc.wantContentType, got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+				t.Errorf("Expected Cache-Control %q, but got %q", "no-cache", got)
+			}
+			if got := w.Body.String(); got != tc.wantBody {
+				t.Errorf("Expected body %q, but got %q", tc.wantBody, got)
+			}
+		})
+	}
+
+	t.Run("does not rewrite html", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/app/", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if strings.Contains(w.Body.String(), "<script") {
+			t.Errorf("Expected index.html to be served untouched, but got %q", w.Body.String())
+		}
+	})
+
+	t.Run("rejects invalid namespace", func(t *testing.T) {
+		if _, err := NewStaticFilesHandler(filesys, WithWebEnvEndpoint("env.js", WebEnvFormatScript, env, "!!!")); !errors.Is(err, ErrCouldNotParseNamespace) {
+			t.Errorf("Expected ErrCouldNotParseNamespace, but got %v", err)
+		}
+	})
+
+	t.Run("rejects duplicate paths", func(t *testing.T) {
+		_, err := NewStaticFilesHandler(filesys,
+			WithWebEnvEndpoint("env.js", WebEnvFormatScript, env, ""),
+			WithWebEnvEndpoint("/env.js", WebEnvFormatModule, env, ""),
+		)
+		if !errors.Is(err, ErrDuplicateWebEnvEndpoint) {
+			t.Errorf("Expected ErrDuplicateWebEnvEndpoint, but got %v", err)
+		}
+	})
+
+	t.Run("rejects unmarshallable env", func(t *testing.T) {
+		if _, err := NewStaticFilesHandler(filesys, WithWebEnvEndpoint("env.js", WebEnvFormatJSON, make(chan int), "")); !errors.Is(err, ErrCouldNotMarshalConfig) {
+			t.Errorf("Expected ErrCouldNotMarshalConfig, but got %v", err)
+		}
+	})
+}